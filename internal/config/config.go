@@ -2,19 +2,53 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// DBConfig database config
+// DBConfig database config. The timeouts are in seconds
 type DBConfig struct {
+	Gateway  string `json:"gateway"`
+	URI      string `json:"uri"`
 	Server   string `json:"server"`
 	Port     string `json:"port"`
 	Username string `json:"username"`
 	Password string `json:"password"`
 	DBName   string `json:"dbname"`
+
+	AuthSource     string `json:"authSource"`
+	AuthMechanism  string `json:"authMechanism"`
+	TLS            bool   `json:"tls"`
+	TLSCAFile      string `json:"tlsCAFile"`
+	ReplicaSet     string `json:"replicaSet"`
+	ReadPreference string `json:"readPreference"`
+	WriteConcern   string `json:"writeConcern"`
+	MaxPoolSize    int    `json:"maxPoolSize"`
+	ConnectTimeout int    `json:"connectTimeout"`
+	SocketTimeout  int    `json:"socketTimeout"`
+
+	// SearchIndex runs the full-text search on the in-process index
+	SearchIndex bool `json:"searchIndex"`
+}
+
+// String returns the config with the credentials masked, so it is safe to log
+func (c DBConfig) String() string {
+	masked := c
+	if masked.Password != "" {
+		masked.Password = "***"
+	}
+	if u, err := url.Parse(masked.URI); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "***")
+			masked.URI = u.String()
+		}
+	}
+	type plain DBConfig
+	return fmt.Sprintf("%+v", plain(masked))
 }
 
 // Config is Server and DB configuration
@@ -23,6 +57,31 @@ type Config struct {
 	Address string   `json:"address"`
 	Port    string   `json:"port"`
 	Timeout int      `json:"timeout"`
+
+	// NutritionTable is the CSV or JSON file of the nutrition table, the
+	// nutrition of the recipes is not computed without it
+	NutritionTable string `json:"nutritionTable"`
+	// IngredientCatalog is the CSV or JSON file of the ingredient catalog,
+	// the allergens and diet labels are not derived without it
+	IngredientCatalog string `json:"ingredientCatalog"`
+	// ImagesDir is the directory of the recipe images, the images cannot
+	// be uploaded without it. ImagesURL is the base URL of the images,
+	// /images/ served by the app by default
+	ImagesDir string `json:"imagesDir"`
+	ImagesURL string `json:"imagesURL"`
+	// ImportToken is the bearer token of the clients importing the recipes
+	// with their ratings, the recipes cannot be imported without it
+	ImportToken string `json:"importToken"`
+}
+
+// String returns the config with the secrets masked, so it is safe to log
+func (c Config) String() string {
+	masked := c
+	if masked.ImportToken != "" {
+		masked.ImportToken = "***"
+	}
+	type plain Config
+	return fmt.Sprintf("%+v", plain(masked))
 }
 
 func getenv(key, fallback string) string {
@@ -33,24 +92,64 @@ func getenv(key, fallback string) string {
 	return value
 }
 
+func getenvInt(key string, fallback int) int {
+	value, err := strconv.ParseInt(getenv(key, strconv.Itoa(fallback)), 10, 32)
+	if err != nil {
+		log.Errorf("Wrong value on %s. It will be set to %d.", key, fallback)
+		return fallback
+	}
+	return int(value)
+}
+
+func getenvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(getenv(key, strconv.FormatBool(fallback)))
+	if err != nil {
+		log.Errorf("Wrong value on %s. It will be set to %t.", key, fallback)
+		return fallback
+	}
+	return value
+}
+
 // GetConfigFromEnv get config from environment variables
 func GetConfigFromEnv() (*Config, error) {
-	timeout, err := strconv.ParseInt(getenv("SRV_TIMEOUT", "10"), 10, 32)
-	if err != nil {
-		log.Error("Wrong value on SRV_TIMEOUT. It will be set to 10.")
-		timeout = 10
+	// The hosts of the URI are overridden by the explicit host only
+	uri := getenv("DB_URI", "")
+	host, port := "mongodb", "27017"
+	if uri != "" {
+		host, port = "", ""
 	}
 	cfg := &Config{
 		DB: DBConfig{
-			Server:   getenv("DB_HOST", "mongodb"),
-			Port:     getenv("DB_PORT", "27017"),
+			Gateway:  getenv("DB_GATEWAY", "mongodb"),
+			URI:      uri,
+			Server:   getenv("DB_HOST", host),
+			Port:     getenv("DB_PORT", port),
 			Username: getenv("DB_USER", ""),
 			Password: getenv("DB_PASS", ""),
 			DBName:   getenv("DB_NAME", "hellofresh"),
+
+			AuthSource:     getenv("DB_AUTH_SOURCE", ""),
+			AuthMechanism:  getenv("DB_AUTH_MECHANISM", ""),
+			TLS:            getenvBool("DB_TLS", false),
+			TLSCAFile:      getenv("DB_TLS_CA_FILE", ""),
+			ReplicaSet:     getenv("DB_REPLICA_SET", ""),
+			ReadPreference: getenv("DB_READ_PREFERENCE", ""),
+			WriteConcern:   getenv("DB_WRITE_CONCERN", ""),
+			MaxPoolSize:    getenvInt("DB_MAX_POOL_SIZE", 0),
+			ConnectTimeout: getenvInt("DB_CONNECT_TIMEOUT", 0),
+			SocketTimeout:  getenvInt("DB_SOCKET_TIMEOUT", 0),
+
+			SearchIndex: getenvBool("DB_SEARCH_INDEX", false),
 		},
 		Address: getenv("SRV_HOST", ""),
 		Port:    getenv("SRV_PORT", "8080"),
-		Timeout: int(timeout),
+		Timeout: getenvInt("SRV_TIMEOUT", 10),
+
+		NutritionTable:    getenv("NUTRITION_TABLE", ""),
+		IngredientCatalog: getenv("INGREDIENT_CATALOG", ""),
+		ImagesDir:         getenv("IMAGES_DIR", ""),
+		ImagesURL:         getenv("IMAGES_URL", ""),
+		ImportToken:       getenv("IMPORT_TOKEN", ""),
 	}
 	return cfg, nil
 }
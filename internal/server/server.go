@@ -1,11 +1,18 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/ashkarin/ashkarin-api-test/pkg/blob"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/catalog"
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/images"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/nutrition"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/search"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -17,6 +24,8 @@ import (
 // Server is the app container
 type Server struct {
 	recipesService *recipes.Service
+	imagesService  *recipes.ImagesService
+	importService  *recipes.ImportService
 	Router         *mux.Router
 	server         *http.Server
 }
@@ -26,25 +35,119 @@ func (s *Server) Initialize(cfg *config.Config) {
 	// Log
 	log.Infof("Initialize server with: %v", cfg)
 
-	// Open a gateway to the MongoDB storage
-	collection := "recipes"
-	recipesStorage, err := gateways.NewMongoDbGateway(cfg.DB.Server, cfg.DB.Port, cfg.DB.Username, cfg.DB.Password, cfg.DB.DBName, collection)
+	// Open a gateway to the recipes storage
+	recipesStorage, err := newRecipesStorage(&cfg.DB)
 	if err != nil {
 		log.Fatalf("Connection to the recipes storage: %v", err)
 	}
-	log.Infof("Connected to the recipes storage: %s@%s:%s/%s/%s", cfg.DB.Username, cfg.DB.Server, cfg.DB.Port, cfg.DB.DBName, collection)
+	if cfg.NutritionTable != "" {
+		table, err := nutrition.Load(cfg.NutritionTable)
+		if err != nil {
+			log.Fatalf("Nutrition table: %v", err)
+		}
+		recipesStorage = nutrition.NewGateway(recipesStorage, table)
+		log.Infof("Compute the nutrition by the table of %d ingredients", table.Len())
+	}
+	if cfg.IngredientCatalog != "" {
+		c, err := catalog.Load(cfg.IngredientCatalog)
+		if err != nil {
+			log.Fatalf("Ingredient catalog: %v", err)
+		}
+		recipesStorage = catalog.NewGateway(recipesStorage, c)
+		log.Infof("Derive the allergens and diets by the catalog of %d ingredients", c.Len())
+	}
+	var blobs blob.Store
+	if cfg.ImagesDir != "" {
+		baseURL := cfg.ImagesURL
+		if baseURL == "" {
+			baseURL = "/images/"
+		}
+		blobs, err = blob.NewFileStore(cfg.ImagesDir, baseURL)
+		if err != nil {
+			log.Fatalf("Images directory: %v", err)
+		}
+		recipesStorage = images.NewGateway(recipesStorage, blobs)
+		log.Infof("Keep the recipe images in %s", cfg.ImagesDir)
+	}
 
 	// Create route and service
 	s.Router = mux.NewRouter()
 	s.recipesService = recipes.NewService(recipesStorage, s.Router)
+	if blobs != nil {
+		s.imagesService = recipes.NewImagesService(recipesStorage, blobs, s.Router)
+	}
+	if cfg.ImportToken != "" {
+		s.importService = recipes.NewImportService(recipesStorage, cfg.ImportToken, s.Router)
+	}
 
-	// Create the server
+	// Create the server. The timeout handler cancels the request context,
+	// so the storage operations are aborted with the request. The zero
+	// timeout means no timeout
+	timeout := time.Duration(cfg.Timeout) * time.Second
 	s.server = &http.Server{
-		Handler:      s.Router,
-		Addr:         fmt.Sprintf("%s:%s", cfg.Address, cfg.Port),
-		WriteTimeout: time.Duration(cfg.Timeout) * time.Second,
-		ReadTimeout:  time.Duration(cfg.Timeout) * time.Second,
+		Handler: s.Router,
+		Addr:    fmt.Sprintf("%s:%s", cfg.Address, cfg.Port),
+	}
+	if timeout > 0 {
+		s.server.Handler = http.TimeoutHandler(s.Router, timeout, `{"error":"Request timeout"}`)
+		s.server.WriteTimeout = timeout + time.Second
+		s.server.ReadTimeout = timeout
+	}
+}
+
+// newRecipesStorage opens the recipes storage gateway selected by the configuration.
+// The mgo based gateway stays the default while the services move to the
+// official MongoDB driver
+func newRecipesStorage(cfg *config.DBConfig) (recipe.StorageGateway, error) {
+	var gw recipe.StorageGateway
+	var err error
+
+	opts := gateways.MongoOptions{
+		URI:            cfg.URI,
+		Server:         cfg.Server,
+		Port:           cfg.Port,
+		Username:       cfg.Username,
+		Password:       cfg.Password,
+		AuthSource:     cfg.AuthSource,
+		AuthMechanism:  cfg.AuthMechanism,
+		TLS:            cfg.TLS,
+		TLSCAFile:      cfg.TLSCAFile,
+		ReplicaSet:     cfg.ReplicaSet,
+		ReadPreference: cfg.ReadPreference,
+		WriteConcern:   cfg.WriteConcern,
+		MaxPoolSize:    uint64(cfg.MaxPoolSize),
+		ConnectTimeout: time.Duration(cfg.ConnectTimeout) * time.Second,
+		SocketTimeout:  time.Duration(cfg.SocketTimeout) * time.Second,
+		Database:       cfg.DBName,
+		Collection:     "recipes",
+
+		TaxonomyCollection: "taxonomy",
+	}
+	switch cfg.Gateway {
+	case "memory":
+		log.Infof("Use the in-memory recipes storage")
+		gw = gateways.NewMemoryGateway()
+	case "", "mongodb", "mgo":
+		gw, err = gateways.NewMongoDbGateway(opts)
+	case "mongo-driver":
+		gw, err = gateways.NewMongoDriverGateway(opts)
+	default:
+		return nil, fmt.Errorf("unknown storage gateway: %s", cfg.Gateway)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Gateway != "memory" {
+		log.Infof("Connected to the recipes storage: %s/%s", cfg.DBName, opts.Collection)
+	}
+
+	if cfg.SearchIndex {
+		if gw, err = search.NewIndexedGateway(context.Background(), gw); err != nil {
+			return nil, err
+		}
+		log.Infof("Use the in-process full-text search index")
 	}
+	return gw, nil
 }
 
 // ListenAndServe start the server
@@ -0,0 +1,84 @@
+package recipes_test
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/catalog"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService diets", func() {
+	ts := serveEach(func(router *mux.Router) recipe.StorageGateway {
+		c, err := catalog.ReadCSV(strings.NewReader(
+			"name,kind,allergens\ntomato,,\nflour,,gluten\nbutter,dairy,milk\nwalnut,,nuts\nchicken,meat,\n"))
+		Expect(err).NotTo(HaveOccurred())
+		return catalog.NewGateway(gateways.NewMemoryGateway(), c)
+	})
+
+	list := func(params string) []string {
+		obtained := struct {
+			Recipes []recipe.Recipe `json:"recipes"`
+		}{}
+		res := doJSON("GET", ts.URL+"/recipes?"+params, "", &obtained)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		var names []string
+		for _, r := range obtained.Recipes {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		for _, body := range []string{
+			`{"name": "Tomato Salad", "difficulty": 1, "ingredients": [{"name": "tomatoes"}, {"name": "walnuts"}]}`,
+			`{"name": "Shortbread", "difficulty": 1, "vegetarian": false, "ingredients": [{"name": "flour"}, {"name": "butter"}]}`,
+			`{"name": "Roast Chicken", "difficulty": 1, "vegetarian": true, "ingredients": [{"name": "chicken"}, {"name": "butter"}]}`,
+			`{"name": "Flatbread", "difficulty": 1, "allergenOverrides": {"remove": ["gluten"]}, "ingredients": [{"name": "flour"}]}`,
+		} {
+			res, _ := doRecipe("POST", ts.URL+"/recipes", body)
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		}
+	})
+
+	It("should derive the diets and allergens from the ingredients", func() {
+		res, created := doRecipe("POST", ts.URL+"/recipes",
+			`{"name": "Walnut Bread", "difficulty": 1, "allergens": ["fish"], "ingredients": [{"name": "flour"}, {"name": "walnuts"}]}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		_, obtained := doRecipe("GET", ts.URL+"/recipes/"+created.IDHex(), "")
+		Expect(obtained.Allergens).To(Equal([]recipe.Allergen{recipe.AllergenGluten, recipe.AllergenNuts}))
+		Expect(obtained.Diets).To(Equal([]recipe.Diet{
+			recipe.DietVegan, recipe.DietVegetarian, recipe.DietPescatarian, recipe.DietDairyFree,
+		}))
+		Expect(obtained.Vegetarian).To(BeTrue())
+	})
+
+	It("should filter the recipes by the diets and allergens", func() {
+		Expect(list("vegetarian=true")).To(ConsistOf("Tomato Salad", "Shortbread", "Flatbread"))
+		Expect(list("diet=vegan")).To(ConsistOf("Tomato Salad", "Flatbread"))
+		Expect(list("diet=vegan,gluten-free")).To(ConsistOf("Tomato Salad", "Flatbread"))
+		Expect(list("diet=vegetarian&diet=nut-free")).To(ConsistOf("Shortbread", "Flatbread"))
+		Expect(list("allergenFree=milk,nuts")).To(ConsistOf("Flatbread"))
+		Expect(list("diet=pescatarian&allergenFree=gluten")).To(ConsistOf("Tomato Salad", "Flatbread"))
+	})
+
+	It("should reject the unknown diets and allergens", func() {
+		for _, params := range []string{"diet=keto", "allergenFree=nothing"} {
+			res := doJSON("GET", ts.URL+"/recipes?"+params, "", nil)
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity), params)
+		}
+
+		for _, body := range []string{
+			`{"name": "Soup", "difficulty": 1, "diets": ["keto"]}`,
+			`{"name": "Soup", "difficulty": 1, "allergenOverrides": {"add": ["nothing"]}}`,
+			`{"name": "Soup", "difficulty": 1, "allergenOverrides": {"add": ["milk"], "remove": ["milk"]}}`,
+		} {
+			res, _ := doRecipe("POST", ts.URL+"/recipes", body)
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity), body)
+		}
+	})
+})
@@ -0,0 +1,66 @@
+package recipes_test
+
+import (
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService durations", func() {
+	ts := serveEach(nil)
+
+	names := func(u string) []string {
+		obtained := map[string]interface{}{}
+		Expect(doJSON("GET", u, "", &obtained).StatusCode).To(Equal(http.StatusOK))
+		var names []string
+		recipes, _ := obtained["recipes"].([]interface{})
+		for _, r := range recipes {
+			names = append(names, r.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		for _, body := range []string{
+			`{"name": "Stew", "difficulty": 1, "prepTime": "PT20M", "cookTime": "PT2H"}`,
+			`{"name": "Salad", "difficulty": 1, "prepTime": "PT15M"}`,
+			`{"name": "Pasta", "difficulty": 1, "prepTime": "PT10M", "cookTime": "PT12M"}`,
+		} {
+			res := doJSON("POST", ts.URL+"/recipes", body, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		}
+	})
+
+	It("should emit the ISO-8601 durations and the total time", func() {
+		created := map[string]interface{}{}
+		res := doJSON("POST", ts.URL+"/recipes", `{"name": "Roast", "difficulty": 1, "prepTime": "PT1H30M", "cookTime": "PT90M", "totalTime": "PT1M"}`, &created)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		Expect(created["totalTime"]).To(Equal("PT3H"))
+
+		obtained := map[string]interface{}{}
+		doJSON("GET", ts.URL+"/recipes/"+created["_id"].(string), "", &obtained)
+		Expect(obtained["prepTime"]).To(Equal("PT1H30M"))
+		Expect(obtained["cookTime"]).To(Equal("PT1H30M"))
+		Expect(obtained["totalTime"]).To(Equal("PT3H"))
+	})
+
+	It("should reject the invalid durations", func() {
+		for _, body := range []string{
+			`{"name": "Bad", "difficulty": 1, "prepTime": "20 minutes"}`,
+			`{"name": "Bad", "difficulty": 1, "cookTime": "P1M"}`,
+			`{"name": "Bad", "difficulty": 1, "prepTime": 20}`,
+		} {
+			res := doJSON("POST", ts.URL+"/recipes", body, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest), body)
+		}
+	})
+
+	It("should sort and filter by the durations", func() {
+		Expect(names(ts.URL + "/recipes?sort=-totalTime")).To(Equal([]string{"Stew", "Pasta", "Salad"}))
+		Expect(names(ts.URL + "/recipes?sort=cookTime")).To(Equal([]string{"Salad", "Pasta", "Stew"}))
+		Expect(names(ts.URL + "/recipes?minCookTime=PT10M&maxTotalTime=PT1H")).To(Equal([]string{"Pasta"}))
+		Expect(names(ts.URL + "/recipes/search?mode=query&q=" + url.QueryEscape("total>20m cook<1h"))).To(Equal([]string{"Pasta"}))
+	})
+})
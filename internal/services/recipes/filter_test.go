@@ -0,0 +1,69 @@
+package recipes_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService filtering", func() {
+	ts := serveEach(nil)
+
+	type page struct {
+		Recipes []recipe.Recipe `json:"recipes"`
+		Total   *int64          `json:"total"`
+		Facets  *recipe.Facets  `json:"facets"`
+	}
+
+	get := func(url string) (*http.Response, page) {
+		obtained := page{}
+		res := doJSON("GET", url, "", &obtained)
+		return res, obtained
+	}
+
+	BeforeEach(func() {
+		entries := []recipe.Recipe{
+			{Name: "Salad", PrepTime: recipe.Duration(10 * time.Minute), Difficulty: recipe.Easy, Vegetarian: true, AverageRating: 4.5, RatingsCount: 10},
+			{Name: "Risotto", PrepTime: recipe.Duration(40 * time.Minute), Difficulty: recipe.Easy, Vegetarian: true, AverageRating: 4.8, RatingsCount: 3},
+			{Name: "Steak", PrepTime: recipe.Duration(25 * time.Minute), Difficulty: recipe.Normal, AverageRating: 4.9, RatingsCount: 20},
+			{Name: "Omelette", PrepTime: recipe.Duration(15 * time.Minute), Difficulty: recipe.Easy, Vegetarian: true, AverageRating: 3.5, RatingsCount: 8},
+			{Name: "Wellington", PrepTime: recipe.Duration(2 * time.Hour), Difficulty: recipe.Hard, AverageRating: 4.2, RatingsCount: 5},
+		}
+		for i := range entries {
+			Expect(ts.storage.Store(context.Background(), &entries[i])).To(Succeed())
+		}
+	})
+
+	It("should filter by the facets and ranges", func() {
+		res, obtained := get(ts.URL + "/recipes?vegetarian=true&difficulty=easy&minRating=4&maxPrepTime=PT30M&total=true")
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(obtained.Recipes).To(HaveLen(1))
+		Expect(obtained.Recipes[0].Name).To(Equal("Salad"))
+		Expect(*obtained.Total).To(Equal(int64(1)))
+	})
+
+	It("should filter by the ratings count", func() {
+		_, obtained := get(ts.URL + "/recipes?minRatingsCount=5&maxRatingsCount=10&sort=name")
+		Expect(obtained.Recipes).To(HaveLen(3))
+		Expect(obtained.Recipes[0].Name).To(Equal("Omelette"))
+	})
+
+	It("should count the facets of the filtered recipes", func() {
+		_, obtained := get(ts.URL + "/recipes?minRating=4")
+		Expect(obtained.Facets).NotTo(BeNil())
+		Expect(obtained.Facets.Difficulty).To(Equal(map[string]int64{"1": 2, "2": 1, "3": 1}))
+		Expect(obtained.Facets.Vegetarian).To(Equal(map[string]int64{"true": 2, "false": 2}))
+	})
+
+	It("should reject invalid filters", func() {
+		res, _ := get(ts.URL + "/recipes?difficulty=extreme")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		res, _ = get(ts.URL + "/recipes?minRating=5&maxRating=4")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
@@ -0,0 +1,131 @@
+package recipes
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ashkarin/ashkarin-api-test/internal/utils"
+	"github.com/ashkarin/ashkarin-api-test/pkg/blob"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/images"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/usecases"
+)
+
+// maxFormOverhead is the size of the multipart form besides the image
+const maxFormOverhead = 1 << 20
+
+// ImagesService provides a set of HTTP handlers for work with the images
+// of the recipes
+type ImagesService struct {
+	storage recipe.StorageGateway
+	blobs   blob.Store
+	router  *mux.Router
+}
+
+// NewImagesService creates a service to work with the images of the recipes
+// kept in the blob store. It serves the blobs by /images/{key} as well
+func NewImagesService(s recipe.StorageGateway, blobs blob.Store, router *mux.Router) *ImagesService {
+	service := &ImagesService{
+		storage: s,
+		blobs:   blobs,
+		router:  router,
+	}
+	service.initializeRoutes()
+
+	return service
+}
+
+func (s *ImagesService) initializeRoutes() {
+	// POST [upload recipe image] ?/recipes/{id}/images
+	s.router.HandleFunc("/recipes/{id}/images", s.AddImage).Methods("POST")
+
+	// GET [list recipe images] ?/recipes/{id}/images
+	s.router.HandleFunc("/recipes/{id}/images", s.ListImages).Methods("GET")
+
+	// DELETE [delete recipe image] ?/recipes/{id}/images/{image}
+	s.router.HandleFunc("/recipes/{id}/images/{image}", s.RemoveImage).Methods("DELETE")
+
+	// GET [get image file] ?/images/{key}
+	s.router.HandleFunc("/images/{key:.+}", s.GetBlob).Methods("GET")
+}
+
+// AddImage is the HTTP handler to upload the image of the recipe in the
+// image field of the multipart form. The image is resized to the variants
+func (s *ImagesService) AddImage(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, images.MaxSize+maxFormOverhead)
+	file, _, err := r.FormFile("image")
+	var merr *http.MaxBytesError
+	if errors.As(err, &merr) {
+		utils.ResponseWithError(w, http.StatusRequestEntityTooLarge, images.ErrTooLarge.Error())
+		return
+	}
+	if err != nil {
+		log.Errorf("AddImage: %v", err)
+		utils.ResponseWithError(w, http.StatusBadRequest, "The image is expected in the image field of the multipart form")
+		return
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, images.MaxSize+1))
+	if err != nil {
+		log.Errorf("AddImage: %v", err)
+		utils.ResponseWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	img, err := usecases.AddRecipeImage(r.Context(), s.storage, s.blobs, mux.Vars(r)["id"], data)
+	if err != nil {
+		log.Errorf("AddImage: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusCreated, img)
+}
+
+// ListImages is the HTTP handler to list the images of the recipe
+func (s *ImagesService) ListImages(w http.ResponseWriter, r *http.Request) {
+	imgs, err := usecases.ListRecipeImages(r.Context(), s.storage, mux.Vars(r)["id"])
+	if err != nil {
+		log.Errorf("ListImages: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusOK, imgs)
+}
+
+// RemoveImage is the HTTP handler to delete the image of the recipe
+func (s *ImagesService) RemoveImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := usecases.RemoveRecipeImage(r.Context(), s.storage, s.blobs, vars["id"], vars["image"]); err != nil {
+		log.Errorf("RemoveImage: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// GetBlob is the HTTP handler to get the image file from the blob store.
+// The keys of the images are never reused, so the files are cached forever
+func (s *ImagesService) GetBlob(w http.ResponseWriter, r *http.Request) {
+	body, contentType, err := s.blobs.Get(r.Context(), mux.Vars(r)["key"])
+	if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+		utils.ResponseWithError(w, http.StatusNotFound, blob.ErrNotFound.Error())
+		return
+	}
+	if err != nil {
+		log.Errorf("GetBlob: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, body)
+}
@@ -0,0 +1,144 @@
+package recipes_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ashkarin/ashkarin-api-test/internal/services/recipes"
+	"github.com/ashkarin/ashkarin-api-test/pkg/blob"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/images"
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService images", func() {
+	var (
+		dir string
+		id  string
+	)
+
+	ts := serveEach(func(router *mux.Router) recipe.StorageGateway {
+		var err error
+		dir, err = ioutil.TempDir("", "images")
+		Expect(err).NotTo(HaveOccurred())
+		blobs, err := blob.NewFileStore(dir, "/images/")
+		Expect(err).NotTo(HaveOccurred())
+
+		storage := images.NewGateway(gateways.NewMemoryGateway(), blobs)
+		_ = recipes.NewImagesService(storage, blobs, router)
+		return storage
+	})
+
+	upload := func(field string, data []byte, obtained interface{}) *http.Response {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile(field, "photo.png")
+		Expect(err).NotTo(HaveOccurred())
+		part.Write(data)
+		form.Close()
+
+		req, err := http.NewRequest("POST", ts.URL+"/recipes/"+id+"/images", &body)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		return doRequest(req, obtained)
+	}
+
+	photo := func() []byte {
+		img := image.NewNRGBA(image.Rect(0, 0, 800, 600))
+		for x := 0; x < 800; x++ {
+			img.Set(x, x%600, color.NRGBA{G: 200, A: 255})
+		}
+		var buf bytes.Buffer
+		Expect(png.Encode(&buf, img)).To(Succeed())
+		return buf.Bytes()
+	}
+
+	BeforeEach(func() {
+		created := recipe.Recipe{}
+		res := doJSON("POST", ts.URL+"/recipes", `{"name": "Pesto Pasta", "difficulty": 1}`, &created)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		id = created.IDHex()
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should upload the image in the resized variants", func() {
+		img := recipe.Image{}
+		Expect(upload("image", photo(), &img).StatusCode).To(Equal(http.StatusCreated))
+		Expect(img.Variants).To(HaveLen(3))
+		Expect(img.Variants["card"].Width).To(Equal(400))
+		Expect(img.Variants["hero"].URL).To(Equal("/images/recipes/" + id + "/" + img.ID + "/hero.jpg"))
+
+		res, err := testClient.Get(ts.URL + img.Variants["thumbnail"].URL)
+		Expect(err).NotTo(HaveOccurred())
+		data, _ := ioutil.ReadAll(res.Body)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.Header.Get("Content-Type")).To(Equal("image/jpeg"))
+		Expect(http.DetectContentType(data)).To(Equal("image/jpeg"))
+
+		// The recipe has the image and keeps it on update
+		obtained := recipe.Recipe{}
+		doJSON("GET", ts.URL+"/recipes/"+id, "", &obtained)
+		Expect(obtained.Images).To(HaveLen(1))
+		Expect(obtained.Images[0].Variants["hero"].URL).To(Equal(img.Variants["hero"].URL))
+		res = doJSON("PUT", ts.URL+"/recipes/"+id, `{"name": "Pesto Pasta", "difficulty": 1, "images": []}`, &obtained)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(obtained.Images).To(HaveLen(1))
+
+		var listed []recipe.Image
+		doJSON("GET", ts.URL+"/recipes/"+id+"/images", "", &listed)
+		Expect(listed).To(HaveLen(1))
+	})
+
+	It("should reject the invalid uploads", func() {
+		Expect(upload("image", []byte("<html><body>not an image</body></html>"), nil).StatusCode).
+			To(Equal(http.StatusUnprocessableEntity))
+		Expect(upload("photo", photo(), nil).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(upload("image", make([]byte, images.MaxSize+1<<20), nil).StatusCode).
+			To(Equal(http.StatusRequestEntityTooLarge))
+
+		id = "5a0d1ae0e5b5e4b0a5a0d1ae"
+		Expect(upload("image", photo(), nil).StatusCode).To(Equal(http.StatusNotFound))
+		Expect(doJSON("GET", ts.URL+"/images/recipes/missing.jpg", "", nil).StatusCode).
+			To(Equal(http.StatusNotFound))
+	})
+
+	It("should delete the image files with the image and the recipe", func() {
+		first, second := recipe.Image{}, recipe.Image{}
+		upload("image", photo(), &first)
+		upload("image", photo(), &second)
+
+		res := doJSON("DELETE", ts.URL+"/recipes/"+id+"/images/"+first.ID, "", nil)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		res = doJSON("DELETE", ts.URL+"/recipes/"+id+"/images/"+first.ID, "", nil)
+		Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(filepath.Join(dir, "recipes", id, first.ID)).NotTo(BeADirectory())
+		Expect(filepath.Join(dir, "recipes", id, second.ID)).To(BeADirectory())
+
+		res = doJSON("DELETE", ts.URL+"/recipes/"+id, "", nil)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(filepath.Join(dir, "recipes")).NotTo(BeADirectory())
+	})
+
+	It("should limit the number of the images", func() {
+		data := photo()
+		for i := 0; i < recipe.MaxImages; i++ {
+			Expect(upload("image", data, nil).StatusCode).To(Equal(http.StatusCreated))
+		}
+		Expect(upload("image", data, nil).StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		entries, _ := ioutil.ReadDir(filepath.Join(dir, "recipes", id))
+		Expect(entries).To(HaveLen(recipe.MaxImages))
+	})
+})
@@ -0,0 +1,76 @@
+package recipes
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ashkarin/ashkarin-api-test/internal/utils"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/usecases"
+)
+
+// ImportService provides the HTTP handler to import the recipes with their
+// ratings. It is for the privileged clients having the import token
+type ImportService struct {
+	storage recipe.StorageGateway
+	token   string
+	router  *mux.Router
+}
+
+// NewImportService creates a service to import the recipes. The clients
+// give the token as the bearer token of the Authorization header
+func NewImportService(s recipe.StorageGateway, token string, router *mux.Router) *ImportService {
+	service := &ImportService{
+		storage: s,
+		token:   token,
+		router:  router,
+	}
+	service.initializeRoutes()
+
+	return service
+}
+
+func (s *ImportService) initializeRoutes() {
+	// POST [import recipe with ratings] ?/recipes/import
+	s.router.HandleFunc("/recipes/import", s.ImportRecipe).Methods("POST")
+}
+
+// authorized tells whether the request has the import token
+func (s *ImportService) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// ImportRecipe is the HTTP handler to create the recipe entry in the
+// storage with the given ratings
+func (s *ImportService) ImportRecipe(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		utils.ResponseWithError(w, http.StatusUnauthorized, "The import token is required")
+		return
+	}
+
+	var recipe recipe.Recipe
+	if err := json.NewDecoder(r.Body).Decode(&recipe); err != nil {
+		log.Errorf("ImportRecipe: %v", err)
+		utils.ResponseWithError(w, http.StatusBadRequest, "Invalid request payload JSON format")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := usecases.ImportRecipe(r.Context(), s.storage, &recipe); err != nil {
+		log.Errorf("ImportRecipe: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusCreated, recipe)
+}
@@ -0,0 +1,57 @@
+package recipes_test
+
+import (
+	"net/http"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService ingredients", func() {
+	ts := serveEach(nil)
+
+	It("should store and return the ingredients", func() {
+		res, created := doRecipe("POST", ts.URL+"/recipes", `{
+			"name": "Spaghetti Bolognese",
+			"difficulty": 2,
+			"ingredients": [
+				{"name": "spaghetti", "quantity": 400, "unit": "g"},
+				{"name": "minced beef", "quantity": 500, "unit": "g", "group": "For the sauce"},
+				{"name": "tomatoes", "quantity": 2, "notes": "chopped", "group": "For the sauce"},
+				{"name": "salt", "notes": "to taste"}
+			]
+		}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+
+		_, obtained := doRecipe("GET", ts.URL+"/recipes/"+created.IDHex(), "")
+		Expect(obtained.Ingredients).To(Equal([]recipe.Ingredient{
+			{Name: "spaghetti", Quantity: 400, Unit: "g"},
+			{Name: "minced beef", Quantity: 500, Unit: "g", Group: "For the sauce"},
+			{Name: "tomatoes", Quantity: 2, Notes: "chopped", Group: "For the sauce"},
+			{Name: "salt", Notes: "to taste"},
+		}))
+
+		res, _ = doRecipe("PUT", ts.URL+"/recipes/"+created.IDHex(),
+			`{"name": "Spaghetti Bolognese", "difficulty": 1, "ingredients": [{"name": "spaghetti", "quantity": 500, "unit": "g"}]}`)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		_, obtained = doRecipe("GET", ts.URL+"/recipes/"+created.IDHex(), "")
+		Expect(obtained.Ingredients).To(Equal([]recipe.Ingredient{{Name: "spaghetti", Quantity: 500, Unit: "g"}}))
+	})
+
+	It("should validate the ingredients", func() {
+		for _, ingredients := range []string{
+			`[{"quantity": 1, "unit": "kg"}]`,
+			`[{"name": "flour", "quantity": -1, "unit": "kg"}]`,
+			`[{"name": "flour", "unit": "kg"}]`,
+		} {
+			res, _ := doRecipe("POST", ts.URL+"/recipes", `{"name": "Bread", "difficulty": 1, "ingredients": `+ingredients+`}`)
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity), ingredients)
+		}
+
+		res, created := doRecipe("POST", ts.URL+"/recipes", `{"name": "Bread", "difficulty": 1}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		res, _ = doRecipe("PUT", ts.URL+"/recipes/"+created.IDHex(), `{"name": "Bread", "difficulty": 1, "ingredients": [{"name": " "}]}`)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
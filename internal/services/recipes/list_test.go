@@ -0,0 +1,57 @@
+package recipes_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService listing", func() {
+	ts := serveEach(nil)
+
+	type page struct {
+		Recipes []recipe.Recipe `json:"recipes"`
+		Next    string          `json:"next"`
+		Prev    string          `json:"prev"`
+		Total   *int64          `json:"total"`
+	}
+
+	get := func(url string) (*http.Response, page) {
+		obtained := page{}
+		res := doJSON("GET", url, "", &obtained)
+		return res, obtained
+	}
+
+	BeforeEach(func() {
+		for _, name := range []string{"Soup", "Pasta", "Curry"} {
+			r := &recipe.Recipe{Name: name, PrepTime: recipe.Duration(20 * time.Minute), Difficulty: recipe.Easy}
+			Expect(ts.storage.Store(context.Background(), r)).To(Succeed())
+		}
+	})
+
+	It("should list the recipes page by page", func() {
+		res, obtained := get(ts.URL + "/recipes?limit=2&sort=name&total=true")
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(obtained.Recipes).To(HaveLen(2))
+		Expect(obtained.Recipes[0].Name).To(Equal("Curry"))
+		Expect(*obtained.Total).To(Equal(int64(3)))
+		Expect(obtained.Prev).To(BeEmpty())
+		Expect(res.Header.Get("Link")).To(ContainSubstring(`rel="next"`))
+
+		res, obtained = get(ts.URL + "/recipes?limit=2&sort=name&cursor=" + obtained.Next)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(obtained.Recipes).To(HaveLen(1))
+		Expect(obtained.Recipes[0].Name).To(Equal("Soup"))
+		Expect(obtained.Next).To(BeEmpty())
+		Expect(res.Header.Get("Link")).To(ContainSubstring(`rel="prev"`))
+	})
+
+	It("should reject unknown sort fields", func() {
+		res, _ := get(ts.URL + "/recipes?sort=calories")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
@@ -0,0 +1,51 @@
+package recipes_test
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/nutrition"
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService nutrition", func() {
+	ts := serveEach(func(router *mux.Router) recipe.StorageGateway {
+		table, err := nutrition.ReadCSV(strings.NewReader(
+			"name,calories,protein,fat,carbohydrates,fiber,sodium,pieceGrams\negg,140,12,10,1,0,140,50\n"))
+		Expect(err).NotTo(HaveOccurred())
+		return nutrition.NewGateway(gateways.NewMemoryGateway(), table)
+	})
+
+	It("should compute the nutrition from the ingredients", func() {
+		res, created := doRecipe("POST", ts.URL+"/recipes", `{
+			"name": "Scrambled Eggs",
+			"difficulty": 2,
+			"servings": 2,
+			"ingredients": [{"name": "eggs", "quantity": 4}, {"name": "chives", "quantity": 1, "unit": "tbsp"}],
+			"nutrition": {"total": {"calories": 1}}
+		}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+
+		_, obtained := doRecipe("GET", ts.URL+"/recipes/"+created.IDHex(), "")
+		Expect(obtained.Nutrition).To(Equal(&recipe.Nutrition{
+			Total:      recipe.Nutrients{Calories: 280, Protein: 24, Fat: 20, Carbohydrates: 2, Sodium: 280},
+			PerServing: &recipe.Nutrients{Calories: 140, Protein: 12, Fat: 10, Carbohydrates: 1, Sodium: 140},
+			Missing:    []string{"chives"},
+		}))
+
+		_, obtained = doRecipe("GET", ts.URL+"/recipes/"+created.IDHex()+"?servings=3", "")
+		Expect(obtained.Nutrition.Total.Calories).To(Equal(420.0))
+		Expect(obtained.Nutrition.PerServing.Calories).To(Equal(140.0))
+
+		res, _ = doRecipe("PUT", ts.URL+"/recipes/"+created.IDHex(),
+			`{"name": "Scrambled Eggs", "difficulty": 1, "servings": 2, "ingredients": [{"name": "eggs", "quantity": 2}]}`)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		_, obtained = doRecipe("GET", ts.URL+"/recipes/"+created.IDHex(), "")
+		Expect(obtained.Nutrition.Total.Calories).To(Equal(140.0))
+		Expect(obtained.Nutrition.Missing).To(BeEmpty())
+	})
+})
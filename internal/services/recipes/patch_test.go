@@ -0,0 +1,69 @@
+package recipes_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService patch", func() {
+	ts := serveEach(nil)
+	var stored *recipe.Recipe
+
+	patch := func(contentType, body string, obtained interface{}) *http.Response {
+		req := CreateHTTPRequest("PATCH", ts.URL+"/recipes/"+stored.IDHex(), body)
+		req.Header.Set("Content-Type", contentType)
+		return doRequest(req, obtained)
+	}
+
+	BeforeEach(func() {
+		stored = &recipe.Recipe{
+			Name:          "Patched",
+			PrepTime:      recipe.Duration(20 * time.Minute),
+			Difficulty:    recipe.Easy,
+			AverageRating: 4.5,
+			RatingsCount:  2,
+		}
+		Expect(ts.storage.Store(context.Background(), stored)).To(Succeed())
+	})
+
+	It("should update only the fields of the merge patch", func() {
+		obtained := recipe.Recipe{}
+		res := patch("application/merge-patch+json", `{"name": "Renamed"}`, &obtained)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(obtained.Name).To(Equal("Renamed"))
+		Expect(obtained.PrepTime).To(Equal(recipe.Duration(20 * time.Minute)))
+		Expect(obtained.AverageRating).To(Equal(4.5))
+		Expect(obtained.RatingsCount).To(Equal(int64(2)))
+	})
+
+	It("should apply the JSON patch", func() {
+		obtained := recipe.Recipe{}
+		res := patch("application/json-patch+json", `[{"op": "replace", "path": "/difficulty", "value": 3}]`, &obtained)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(obtained.Difficulty).To(Equal(recipe.Hard))
+		Expect(obtained.Name).To(Equal("Patched"))
+	})
+
+	It("should protect the ratings", func() {
+		res := patch("application/merge-patch+json", `{"averageRating": 5}`, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		res = patch("application/json-patch+json", `[{"op": "replace", "path": "/ratingsCount", "value": 100}]`, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("should reject the failed JSON patch test", func() {
+		res := patch("application/json-patch+json", `[{"op": "test", "path": "/name", "value": "Other"}]`, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("should reject unknown patch formats", func() {
+		res := patch("application/json", `{"name": "Renamed"}`, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusUnsupportedMediaType))
+	})
+})
@@ -1,16 +1,33 @@
 package recipes
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/ashkarin/ashkarin-api-test/internal/utils"
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/images"
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/usecases"
+	"github.com/ashkarin/ashkarin-api-test/pkg/units"
+)
+
+// Limits of the recipes page
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
 )
 
 // Service provides a set of HTTP handlers for work with recipes
@@ -30,21 +47,104 @@ func NewService(s recipe.StorageGateway, router *mux.Router) *Service {
 	return service
 }
 
+// errorStatus maps the recipe errors to the HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, recipe.ErrNotFound), errors.Is(err, recipe.ErrTermNotFound),
+		errors.Is(err, recipe.ErrImageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, recipe.ErrInvalidID):
+		return http.StatusBadRequest
+	case errors.Is(err, recipe.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, recipe.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, images.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// responseWithRecipeError responses with the error and its HTTP status code.
+// The errors of the search query also have their position in the query,
+// and the validation errors of the recipe have the list of the fields
+func responseWithRecipeError(w http.ResponseWriter, err error) {
+	var verr *recipe.ValidationError
+	if errors.As(err, &verr) {
+		utils.ResponseWithJSON(w, errorStatus(err), map[string]interface{}{
+			"error":  err.Error(),
+			"errors": verr.Fields,
+		})
+		return
+	}
+	var qerr *recipe.QueryError
+	if errors.As(err, &qerr) {
+		utils.ResponseWithJSON(w, errorStatus(err), map[string]interface{}{
+			"error":    err.Error(),
+			"position": qerr.Pos,
+		})
+		return
+	}
+	utils.ResponseWithError(w, errorStatus(err), err.Error())
+}
+
+// etag returns the entity tag of the recipe version. The representations
+// of the version, e.g. the scaled recipe, are told apart by the variants,
+// which are appended to the version as "3-s4-imperial"
+func etag(version int64, variants ...string) string {
+	tag := strconv.FormatInt(version, 10)
+	for _, v := range variants {
+		if v != "" {
+			tag += "-" + v
+		}
+	}
+	return `"` + tag + `"`
+}
+
+// parseETag returns the recipe version of the entity tag of the stored
+// representation. The tags of the variants are invalid, so the scaled or
+// converted recipe is never written over the stored one
+func parseETag(tag string) (int64, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
 func (s *Service) initializeRoutes() {
 	s.router.HandleFunc("/", s.IsAlive).Methods("GET")
 
 	// POST [create recipe] ?/recipes
 	s.router.HandleFunc("/recipes", s.СreateRecipe).Methods("POST")
 
+	// GET [search recipes page] ?/recipes/search?q=&mode=&fuzzy=&offset=&limit=
+	s.router.HandleFunc("/recipes/search", s.SearchRecipesPage).Methods("GET")
+
+	// GET [suggest recipe names] ?/recipes/suggest?q=&limit=
+	s.router.HandleFunc("/recipes/suggest", s.SuggestRecipes).Methods("GET")
+
 	// GET [get recipe] ?/recipes/{id}
 	s.router.HandleFunc("/recipes/{id}", s.GetRecipe).Methods("GET")
 
 	// PUT [update recipe] ?/recipes/{id}
 	s.router.HandleFunc("/recipes/{id}", s.UpdateRecipe).Methods("PUT")
 
+	// PATCH [partially update recipe] ?/recipes/{id}
+	s.router.HandleFunc("/recipes/{id}", s.PatchRecipe).Methods("PATCH")
+
 	// DELETE [delete recipe] ?/recipes/{id}
 	s.router.HandleFunc("/recipes/{id}", s.DeleteRecipe).Methods("DELETE")
 
+	// GET [get recipes page] ?/recipes?cursor=&limit=&sort=&total=
+	s.router.HandleFunc("/recipes", s.ListRecipesPage).Methods("GET")
+
 	// GET [get recipes list] ?/recipes/{start:[0-9]+}/{limit:[0-9]+}
 	s.router.HandleFunc("/recipes/{start:[0-9]+}/{limit:[0-9]+}", s.ListRecipes).Methods("GET")
 
@@ -53,6 +153,21 @@ func (s *Service) initializeRoutes() {
 
 	// GET [search recipes by name] ?/recipes/search/{name}
 	s.router.HandleFunc("/recipes/search/{search:.+}", s.SearchRecipes).Methods("GET")
+
+	// GET [list taxonomy terms] ?/{cuisines|meals|tags}
+	s.router.HandleFunc("/{kind:cuisines|meals|tags}", s.ListTerms).Methods("GET")
+
+	// POST [create taxonomy term] ?/{cuisines|meals|tags}
+	s.router.HandleFunc("/{kind:cuisines|meals|tags}", s.CreateTerm).Methods("POST")
+
+	// GET [get taxonomy term] ?/{cuisines|meals|tags}/{slug}
+	s.router.HandleFunc("/{kind:cuisines|meals|tags}/{slug}", s.GetTerm).Methods("GET")
+
+	// PUT [update or rename taxonomy term] ?/{cuisines|meals|tags}/{slug}
+	s.router.HandleFunc("/{kind:cuisines|meals|tags}/{slug}", s.UpdateTerm).Methods("PUT")
+
+	// DELETE [delete taxonomy term] ?/{cuisines|meals|tags}/{slug}
+	s.router.HandleFunc("/{kind:cuisines|meals|tags}/{slug}", s.DeleteTerm).Methods("DELETE")
 }
 
 // IsAlive is the HTTP handler to check whether the service is alive or not
@@ -72,51 +187,183 @@ func (s *Service) СreateRecipe(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	// Create the recipe in the storage
-	if err := usecases.CreateRecipe(s.storage, &recipe); err != nil {
+	if err := usecases.CreateRecipe(r.Context(), s.storage, &recipe); err != nil {
 		log.Errorf("CreateRecipe: %v", err)
-		utils.ResponseWithError(w, http.StatusInternalServerError, err.Error())
+		responseWithRecipeError(w, err)
 		return
 	}
 	utils.ResponseWithJSON(w, http.StatusCreated, recipe)
 }
 
-// GetRecipe is the HTTP handler to get the recipe from storage by ID
+// parseUnits parses the units parameter, metric or imperial. No system
+// keeps the units the recipes are stored in
+func parseUnits(params url.Values) (units.System, error) {
+	v := params.Get("units")
+	if v == "" {
+		return units.AnySystem, nil
+	}
+	sys, err := units.ParseSystem(v)
+	if err != nil {
+		return units.AnySystem, fmt.Errorf("%w: %v", recipe.ErrValidation, err)
+	}
+	return sys, nil
+}
+
+// inUnits converts the recipes of the response to the system of units
+func inUnits(recipes []*recipe.Recipe, sys units.System) {
+	if sys == units.AnySystem {
+		return
+	}
+	for i, r := range recipes {
+		recipes[i] = r.InUnits(sys)
+	}
+}
+
+// GetRecipe is the HTTP handler to get the recipe from storage by ID. The
+// ingredients are scaled if the servings parameter is given and converted
+// if the units parameter is given
 func (s *Service) GetRecipe(w http.ResponseWriter, r *http.Request) {
 	// Get the recipe ID
 	vars := mux.Vars(r)
 	id := vars["id"]
+	sys, err := parseUnits(r.URL.Query())
+	if err != nil {
+		responseWithRecipeError(w, err)
+		return
+	}
 
 	// Get the recipe
-	recipe, err := usecases.GetRecipe(s.storage, id)
+	var recipe *recipe.Recipe
+	var scaled string
+	if v := r.URL.Query().Get("servings"); v != "" {
+		servings, perr := strconv.Atoi(v)
+		if perr != nil {
+			utils.ResponseWithError(w, http.StatusBadRequest, "servings must be a number")
+			return
+		}
+		recipe, err = usecases.ScaleRecipe(r.Context(), s.storage, id, servings)
+		scaled = "s" + strconv.Itoa(servings)
+	} else {
+		recipe, err = usecases.GetRecipe(r.Context(), s.storage, id)
+	}
 	if err != nil {
 		log.Errorf("GetRecipe: %v", err)
-		utils.ResponseWithError(w, http.StatusBadRequest, err.Error())
+		responseWithRecipeError(w, err)
+		return
+	}
+
+	tag := etag(recipe.Version, scaled, string(sys))
+	w.Header().Set("ETag", tag)
+	if match := r.Header.Get("If-None-Match"); match == tag || match == "*" {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
+	if sys != units.AnySystem {
+		recipe = recipe.InUnits(sys)
+	}
 	utils.ResponseWithJSON(w, http.StatusOK, recipe)
 }
 
-// UpdateRecipe is the HTTP handler to update the recipe entry in the storage
+// UpdateRecipe is the HTTP handler to update the recipe entry in the storage.
+// The update is conditional if the If-Match header or the version is given
 func (s *Service) UpdateRecipe(w http.ResponseWriter, r *http.Request) {
 	// Get the recipe ID
 	vars := mux.Vars(r)
-	recipe := recipe.Recipe{ID: vars["id"]}
+	updated := recipe.Recipe{ID: vars["id"]}
 
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&recipe); err != nil {
+	if err := decoder.Decode(&updated); err != nil {
 		log.Errorf("UpdateRecipe: %v", err)
 		utils.ResponseWithError(w, http.StatusBadRequest, "Invalid resquest payload")
 		return
 	}
 	defer r.Body.Close()
 
+	// The If-Match header takes precedence over the version in the payload
+	match := r.Header.Get("If-Match")
+	if match != "" && match != "*" {
+		version, ok := parseETag(match)
+		if !ok {
+			utils.ResponseWithError(w, http.StatusPreconditionFailed, "Invalid If-Match header")
+			return
+		}
+		updated.Version = version
+	}
+
 	// Update the recipe in the storage
-	if err := usecases.UpdateRecipe(s.storage, &recipe); err != nil {
+	if err := usecases.UpdateRecipe(r.Context(), s.storage, &updated); err != nil {
 		log.Errorf("UpdateRecipe: %v", err)
-		utils.ResponseWithError(w, http.StatusInternalServerError, err.Error())
+		if match != "" && errors.Is(err, recipe.ErrConflict) {
+			utils.ResponseWithError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
+		responseWithRecipeError(w, err)
 		return
 	}
-	utils.ResponseWithJSON(w, http.StatusOK, recipe)
+	w.Header().Set("ETag", etag(updated.Version))
+	utils.ResponseWithJSON(w, http.StatusOK, updated)
+}
+
+// PatchRecipe is the HTTP handler to partially update the recipe entry in the storage.
+// It accepts JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902) documents
+func (s *Service) PatchRecipe(w http.ResponseWriter, r *http.Request) {
+	// Get the recipe ID
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("PatchRecipe: %v", err)
+		utils.ResponseWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	// Choose the patch by the content type
+	var patch usecases.PatchFunc
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/merge-patch+json":
+		patch = func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, body)
+		}
+	case "application/json-patch+json":
+		ops, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			log.Errorf("PatchRecipe: %v", err)
+			utils.ResponseWithError(w, http.StatusBadRequest, "Invalid JSON Patch document")
+			return
+		}
+		patch = ops.Apply
+	default:
+		utils.ResponseWithError(w, http.StatusUnsupportedMediaType,
+			"Use application/merge-patch+json or application/json-patch+json")
+		return
+	}
+
+	var version int64
+	match := r.Header.Get("If-Match")
+	if match != "" && match != "*" {
+		var ok bool
+		if version, ok = parseETag(match); !ok {
+			utils.ResponseWithError(w, http.StatusPreconditionFailed, "Invalid If-Match header")
+			return
+		}
+	}
+
+	// Patch the recipe in the storage
+	updated, err := usecases.PatchRecipe(r.Context(), s.storage, id, version, patch)
+	if err != nil {
+		log.Errorf("PatchRecipe: %v", err)
+		if match != "" && errors.Is(err, recipe.ErrConflict) {
+			utils.ResponseWithError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
+		responseWithRecipeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(updated.Version))
+	utils.ResponseWithJSON(w, http.StatusOK, updated)
 }
 
 // DeleteRecipe is the HTTP handler to delete the recipe from the storage
@@ -126,9 +373,9 @@ func (s *Service) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 
 	// Delete the recipe
-	if err := usecases.DeleteRecipeByID(s.storage, id); err != nil {
+	if err := usecases.DeleteRecipeByID(r.Context(), s.storage, id); err != nil {
 		log.Errorf("DeleteRecipe: %v", err)
-		utils.ResponseWithError(w, http.StatusInternalServerError, err.Error())
+		responseWithRecipeError(w, err)
 		return
 	}
 	utils.ResponseWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
@@ -136,6 +383,12 @@ func (s *Service) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
 
 // ListRecipes is the HTTP handler to list all the recipes in the storage
 func (s *Service) ListRecipes(w http.ResponseWriter, r *http.Request) {
+	sys, err := parseUnits(r.URL.Query())
+	if err != nil {
+		responseWithRecipeError(w, err)
+		return
+	}
+
 	// Get get the range of requested entries
 	vars := mux.Vars(r)
 	start, err := strconv.ParseUint(vars["start"], 10, 64)
@@ -149,15 +402,204 @@ func (s *Service) ListRecipes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call the related usecase
-	recipes, err := usecases.ListRecipes(s.storage, start, limit)
+	recipes, err := usecases.ListRecipes(r.Context(), s.storage, start, limit)
 	if err != nil {
 		log.Errorf("ListRecipes: %v", err)
-		utils.ResponseWithError(w, http.StatusInternalServerError, err.Error())
+		responseWithRecipeError(w, err)
 		return
 	}
+	inUnits(recipes, sys)
 	utils.ResponseWithJSON(w, http.StatusOK, recipes)
 }
 
+// pageResponse is the page of the recipes
+type pageResponse struct {
+	Recipes []*recipe.Recipe `json:"recipes"`
+	Next    string           `json:"next,omitempty"`
+	Prev    string           `json:"prev,omitempty"`
+	Total   *int64           `json:"total,omitempty"`
+	Facets  *recipe.Facets   `json:"facets,omitempty"`
+}
+
+// parseFilter parses the recipes filter from the query parameters:
+// difficulty (names or numbers, comma separated), vegetarian, minRating,
+// maxRating, minRatingsCount, maxRatingsCount, minPrepTime, maxPrepTime,
+// minCookTime, maxCookTime, minTotalTime and maxTotalTime (ISO-8601
+// durations)
+func parseFilter(params url.Values) (*recipe.Filter, error) {
+	filter := &recipe.Filter{}
+	invalid := func(name string) error {
+		return fmt.Errorf("%w: invalid value of %s", recipe.ErrValidation, name)
+	}
+
+	for _, v := range params["difficulty"] {
+		for _, name := range strings.Split(v, ",") {
+			d, err := recipe.ParseDifficulty(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			filter.Difficulties = append(filter.Difficulties, d)
+		}
+	}
+	if v := params.Get("vegetarian"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, invalid("vegetarian")
+		}
+		filter.Vegetarian = &b
+	}
+	for _, v := range params["diet"] {
+		for _, name := range strings.Split(v, ",") {
+			d, err := recipe.ParseDiet(name)
+			if err != nil {
+				return nil, err
+			}
+			filter.Diets = append(filter.Diets, d)
+		}
+	}
+	for _, v := range params["allergenFree"] {
+		for _, name := range strings.Split(v, ",") {
+			a, err := recipe.ParseAllergen(name)
+			if err != nil {
+				return nil, err
+			}
+			filter.AllergenFree = append(filter.AllergenFree, a)
+		}
+	}
+	terms := map[string]*[]string{"cuisine": &filter.Cuisines, "meal": &filter.Meals, "tag": &filter.Tags}
+	for name, field := range terms {
+		for _, v := range params[name] {
+			for _, slug := range strings.Split(v, ",") {
+				if slug = recipe.Slugify(slug); slug != "" {
+					*field = append(*field, slug)
+				}
+			}
+		}
+	}
+
+	floats := map[string]**float64{"minRating": &filter.MinRating, "maxRating": &filter.MaxRating}
+	for name, field := range floats {
+		if v := params.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, invalid(name)
+			}
+			*field = &f
+		}
+	}
+	ints := map[string]**int64{"minRatingsCount": &filter.MinRatingsCount, "maxRatingsCount": &filter.MaxRatingsCount}
+	for name, field := range ints {
+		if v := params.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, invalid(name)
+			}
+			*field = &n
+		}
+	}
+	durations := map[string]**time.Duration{
+		"minPrepTime":  &filter.MinPrepTime,
+		"maxPrepTime":  &filter.MaxPrepTime,
+		"minCookTime":  &filter.MinCookTime,
+		"maxCookTime":  &filter.MaxCookTime,
+		"minTotalTime": &filter.MinTotalTime,
+		"maxTotalTime": &filter.MaxTotalTime,
+	}
+	for name, field := range durations {
+		if v := params.Get(name); v != "" {
+			d, err := recipe.ParseISODuration(v)
+			if err != nil {
+				return nil, invalid(name)
+			}
+			*field = &d
+		}
+	}
+	return filter, nil
+}
+
+// ListRecipesPage is the HTTP handler to list the page of the sorted and
+// filtered recipes with the facets. The adjacent pages are given by the
+// opaque cursors in the response and the Link header
+func (s *Service) ListRecipesPage(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	filter, err := parseFilter(params)
+	if err != nil {
+		responseWithRecipeError(w, err)
+		return
+	}
+	sys, err := parseUnits(params)
+	if err != nil {
+		responseWithRecipeError(w, err)
+		return
+	}
+
+	sortParam := params.Get("sort")
+	if sortParam == "" {
+		sortParam = string(recipe.SortByName)
+	}
+	sort, err := recipe.ParseSort(sortParam)
+	if err != nil {
+		responseWithRecipeError(w, err)
+		return
+	}
+
+	limit := defaultPageLimit
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			utils.ResponseWithError(w, http.StatusBadRequest, fmt.Sprintf("limit must be from 1 to %d", maxPageLimit))
+			return
+		}
+		limit = n
+	}
+
+	q := recipe.RangeQuery{Sort: sort, Filter: filter, Limit: limit}
+	if v := params.Get("cursor"); v != "" {
+		if q.Cursor, err = recipe.DecodeCursor(v, sort); err != nil {
+			responseWithRecipeError(w, err)
+			return
+		}
+	}
+	withTotal, _ := strconv.ParseBool(params.Get("total"))
+	withFacets := params.Get("facets") != "false"
+
+	// Call the related usecase
+	page, err := usecases.ListRecipesPage(r.Context(), s.storage, q, withTotal, withFacets)
+	if err != nil {
+		log.Errorf("ListRecipesPage: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+
+	inUnits(page.Recipes, sys)
+	res := pageResponse{Recipes: page.Recipes, Total: page.Total, Facets: page.Facets}
+	if res.Recipes == nil {
+		res.Recipes = []*recipe.Recipe{}
+	}
+	var links []string
+	link := func(c *recipe.Cursor, rel string) string {
+		u := *r.URL
+		query := u.Query()
+		query.Set("cursor", c.Encode())
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("sort", sort.String())
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel))
+		return c.Encode()
+	}
+	if page.Next != nil {
+		res.Next = link(page.Next, "next")
+	}
+	if page.Prev != nil {
+		res.Prev = link(page.Prev, "prev")
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	utils.ResponseWithJSON(w, http.StatusOK, res)
+}
+
 // RateRecipe is the HTTP handler to rate the recipe
 func (s *Service) RateRecipe(w http.ResponseWriter, r *http.Request) {
 	// Get the recipe ID and given score
@@ -167,36 +609,151 @@ func (s *Service) RateRecipe(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		log.Errorf("RateRecipe: %v", err)
-		utils.ResponseWithError(w, http.StatusInternalServerError, err.Error())
+		utils.ResponseWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Rate recipe
-	if err := usecases.RateRecipeByID(s.storage, id, uint8(score)); err != nil {
+	if err := usecases.RateRecipeByID(r.Context(), s.storage, id, uint8(score)); err != nil {
 		log.Errorf("RateRecipe: %v", err)
-		utils.ResponseWithError(w, http.StatusInternalServerError, err.Error())
+		responseWithRecipeError(w, err)
 		return
 	}
 	utils.ResponseWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
 }
 
-// SearchRecipes is the HTTP handler to search the recipes
+// searchResponse is the page of the found recipes
+type searchResponse struct {
+	Recipes     []*recipe.SearchHit `json:"recipes"`
+	Total       int64               `json:"total"`
+	Offset      int                 `json:"offset"`
+	Limit       int                 `json:"limit"`
+	Suggestions []string            `json:"suggestions,omitempty"`
+}
+
+// SearchRecipes is the HTTP handler to search the recipes by name. It
+// takes the parameters of SearchRecipesPage, but responds with the array
+// of the found recipes and gives their total in the X-Total-Count header
 func (s *Service) SearchRecipes(w http.ResponseWriter, r *http.Request) {
-	// Get the name pattern
+	// Get the search text
 	vars := mux.Vars(r)
 	search := vars["search"]
 	if search == "" {
 		log.Errorf("SearchRecipes: No search pattern given")
-		utils.ResponseWithError(w, http.StatusInternalServerError, "No search pattern given")
+		utils.ResponseWithError(w, http.StatusBadRequest, "No search pattern given")
 		return
 	}
 
+	_, found := s.search(w, r, search)
+	if found == nil {
+		return
+	}
+	hits := found.Hits
+	if hits == nil {
+		hits = []*recipe.SearchHit{}
+	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(found.Total, 10))
+	utils.ResponseWithJSON(w, http.StatusOK, hits)
+}
+
+// SearchRecipesPage is the HTTP handler to search the recipes by the name
+// given by the q parameter. The mode parameter is one of text (default),
+// prefix, fulltext, regex and query (see recipe.ParseQuery), the fuzzy
+// parameter sets the typo tolerance and the page is given by the offset
+// and limit parameters
+func (s *Service) SearchRecipesPage(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("q")
+	if search == "" {
+		log.Errorf("SearchRecipesPage: No search pattern given")
+		utils.ResponseWithError(w, http.StatusBadRequest, "No search pattern given")
+		return
+	}
+
+	q, found := s.search(w, r, search)
+	if found == nil {
+		return
+	}
+	res := searchResponse{
+		Recipes:     found.Hits,
+		Total:       found.Total,
+		Offset:      q.Offset,
+		Limit:       q.Limit,
+		Suggestions: found.Suggestions,
+	}
+	if res.Recipes == nil {
+		res.Recipes = []*recipe.SearchHit{}
+	}
+	utils.ResponseWithJSON(w, http.StatusOK, res)
+}
+
+// search searches the recipes by the text and the query parameters of the
+// request. It responds with the error itself and returns the nil result then
+func (s *Service) search(w http.ResponseWriter, r *http.Request, search string) (*recipe.SearchQuery, *recipe.SearchResult) {
+	params := r.URL.Query()
+	mode, err := recipe.ParseSearchMode(params.Get("mode"))
+	if err != nil {
+		responseWithRecipeError(w, err)
+		return nil, nil
+	}
+	fuzziness, err := recipe.ParseFuzziness(params.Get("fuzzy"))
+	if err != nil {
+		responseWithRecipeError(w, err)
+		return nil, nil
+	}
+	sys, err := parseUnits(params)
+	if err != nil {
+		responseWithRecipeError(w, err)
+		return nil, nil
+	}
+	q := recipe.SearchQuery{Text: search, Mode: mode, Fuzziness: fuzziness, Limit: defaultPageLimit}
+	if v := params.Get("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil {
+			utils.ResponseWithError(w, http.StatusBadRequest, "Invalid offset")
+			return nil, nil
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			utils.ResponseWithError(w, http.StatusBadRequest, "Invalid limit")
+			return nil, nil
+		}
+	}
+
 	// Search the recipes
-	recipes, err := usecases.SearchRecipes(s.storage, search)
+	found, err := usecases.SearchRecipes(r.Context(), s.storage, q)
 	if err != nil {
 		log.Errorf("SearchRecipes: %v", err)
-		utils.ResponseWithError(w, http.StatusInternalServerError, err.Error())
+		responseWithRecipeError(w, err)
+		return nil, nil
+	}
+
+	if sys != units.AnySystem {
+		for _, hit := range found.Hits {
+			hit.Recipe = hit.Recipe.InUnits(sys)
+		}
+	}
+	return &q, found
+}
+
+// SuggestRecipes is the HTTP handler to complete the recipe names as the
+// user types. It returns the IDs and names of the most popular recipes
+// which name words start with the words of the q parameter
+func (s *Service) SuggestRecipes(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := recipe.SuggestQuery{Text: params.Get("q"), Limit: defaultPageLimit}
+	if v := params.Get("limit"); v != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			utils.ResponseWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	suggestions, err := usecases.SuggestRecipes(r.Context(), s.storage, q)
+	if err != nil {
+		log.Errorf("SuggestRecipes: %v", err)
+		responseWithRecipeError(w, err)
 		return
 	}
-	utils.ResponseWithJSON(w, http.StatusOK, recipes)
+	utils.ResponseWithJSON(w, http.StatusOK, suggestions)
 }
@@ -2,12 +2,18 @@ package recipes_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/ashkarin/ashkarin-api-test/internal/services/recipes"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
 	"github.com/gorilla/mux"
 	. "github.com/onsi/ginkgo"
@@ -23,35 +29,104 @@ func TestRecipes(t *testing.T) {
 }
 
 var _ = BeforeSuite(func() {
-	// Open a gateway to the MongoDB storage
-	dbhost := "mongodb"
-	dbport := "27017"
-	dbuser := ""
-	dbpassword := ""
-	dbname := "test_db"
-	dbcollection := "testrecipes"
+	// Open a gateway to the storage, the in-memory one is used unless
+	// DB_GATEWAY asks for MongoDB
+	dbgateway := os.Getenv("DB_GATEWAY")
+	dbopts := gateways.MongoOptions{
+		Server:     "mongodb",
+		Port:       "27017",
+		Database:   "test_db",
+		Collection: "testrecipes",
+	}
 	ctx = context.Background()
 
-	recipesStorage, err := gateways.NewMongoDbGateway(dbhost, dbport, dbuser, dbpassword, dbname, dbcollection)
+	var recipesStorage recipe.StorageGateway
+	var err error
+	switch dbgateway {
+	case "mongodb":
+		recipesStorage, err = gateways.NewMongoDbGateway(dbopts)
+	case "mongo-driver":
+		recipesStorage, err = gateways.NewMongoDriverGateway(dbopts)
+	default:
+		recipesStorage = gateways.NewMemoryGateway()
+	}
 	Expect(err).NotTo(HaveOccurred())
 
-	go func() {
-		// Create route and service
-		router := mux.NewRouter()
-		_ = recipes.NewService(recipesStorage, router)
-
-		// Create the server
-		server = &http.Server{
-			Handler:      router,
-			Addr:         fmt.Sprintf("%s:%s", "", "9090"),
-			WriteTimeout: time.Duration(10) * time.Second,
-			ReadTimeout:  time.Duration(10) * time.Second,
-		}
+	// Create route and service
+	router := mux.NewRouter()
+	_ = recipes.NewService(recipesStorage, router)
 
-		server.ListenAndServe()
-	}()
+	// Create the server and listen before serving, so the specs never
+	// run ahead of it
+	server = &http.Server{
+		Handler:      router,
+		Addr:         fmt.Sprintf("%s:%s", "", "9090"),
+		WriteTimeout: time.Duration(10) * time.Second,
+		ReadTimeout:  time.Duration(10) * time.Second,
+	}
+	listener, err := net.Listen("tcp", server.Addr)
+	Expect(err).NotTo(HaveOccurred())
+
+	go server.Serve(listener)
 })
 
 var _ = AfterSuite(func() {
 	server.Shutdown(ctx)
 })
+
+// testServer is the service started for every spec by serveEach
+type testServer struct {
+	*httptest.Server
+	storage recipe.StorageGateway
+}
+
+// serveEach starts the service before every spec of the container and
+// closes it after the spec, so the specs do not depend on the order they
+// run in. The setup returns the storage of the service and registers the
+// other services of the specs on the router, the fresh in-memory storage
+// is used without it
+func serveEach(setup func(router *mux.Router) recipe.StorageGateway) *testServer {
+	ts := &testServer{}
+	BeforeEach(func() {
+		router := mux.NewRouter()
+		ts.storage = gateways.NewMemoryGateway()
+		if setup != nil {
+			ts.storage = setup(router)
+		}
+		_ = recipes.NewService(ts.storage, router)
+		ts.Server = httptest.NewServer(router)
+	})
+	AfterEach(func() {
+		ts.Close()
+	})
+	return ts
+}
+
+// testClient sends the requests of the specs
+var testClient = &http.Client{Timeout: time.Duration(timeout)}
+
+// doRequest sends the request and decodes the JSON response into obtained
+// unless it is nil
+func doRequest(req *http.Request, obtained interface{}) *http.Response {
+	res, err := testClient.Do(req)
+	Expect(err).NotTo(HaveOccurred())
+	data, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if obtained != nil {
+		json.Unmarshal(data, obtained)
+	}
+	return res
+}
+
+// doJSON sends the request with the JSON body, see doRequest
+func doJSON(method, url, body string, obtained interface{}) *http.Response {
+	return doRequest(CreateHTTPRequest(method, url, body), obtained)
+}
+
+// doRecipe sends the request with the JSON body and decodes the recipe of
+// the response
+func doRecipe(method, url, body string) (*http.Response, recipe.Recipe) {
+	obtained := recipe.Recipe{}
+	res := doJSON(method, url, body, &obtained)
+	return res, obtained
+}
@@ -51,7 +51,7 @@ var _ = Describe("RecipesService", func() {
 	It("should create a recipe", func() {
 		expected := recipe.Recipe{
 			Name:          "My Recipe",
-			PrepTime:      "PT20M",
+			PrepTime:      recipe.Duration(20 * time.Minute),
 			Difficulty:    recipe.Easy,
 			Vegetarian:    true,
 			AverageRating: averageRating,
@@ -76,8 +76,9 @@ var _ = Describe("RecipesService", func() {
 			Expect(expected.PrepTime).To(Equal(obtained.PrepTime))
 			Expect(int(expected.Difficulty)).To(Equal(int(obtained.Difficulty)))
 			Expect(expected.Vegetarian).To(Equal(obtained.Vegetarian))
-			Expect(expected.AverageRating).To(Equal(obtained.AverageRating))
-			Expect(expected.RatingsCount).To(Equal(obtained.RatingsCount))
+			// The given ratings are ignored
+			Expect(obtained.AverageRating).To(BeZero())
+			Expect(obtained.RatingsCount).To(BeZero())
 		}
 	})
 
@@ -126,16 +127,15 @@ var _ = Describe("RecipesService", func() {
 			json.Unmarshal(body, &obtained)
 			Expect(obtained.ID.(string)).To(Equal(recipeID))
 
-			expectedRating := averageRating + (float64(score)-averageRating)/float64(ratingsCount+1)
-			Expect(obtained.RatingsCount).To(Equal(ratingsCount + 1))
-			Expect(obtained.AverageRating).To(Equal(expectedRating))
+			Expect(obtained.RatingsCount).To(Equal(int64(1)))
+			Expect(obtained.AverageRating).To(Equal(float64(score)))
 		}
 	})
 
 	It("should update a single recipe by id", func() {
 		expected := recipe.Recipe{
 			Name:          "Updated",
-			PrepTime:      "PT20M",
+			PrepTime:      recipe.Duration(20 * time.Minute),
 			Difficulty:    1,
 			Vegetarian:    true,
 			AverageRating: averageRating,
@@ -159,8 +159,9 @@ var _ = Describe("RecipesService", func() {
 			Expect(expected.PrepTime).To(Equal(obtained.PrepTime))
 			Expect(int(expected.Difficulty)).To(Equal(int(obtained.Difficulty)))
 			Expect(expected.Vegetarian).To(Equal(obtained.Vegetarian))
-			Expect(expected.AverageRating).To(Equal(obtained.AverageRating))
-			Expect(expected.RatingsCount).To(Equal(obtained.RatingsCount))
+			// The stored ratings are kept
+			Expect(obtained.AverageRating).To(Equal(float64(score)))
+			Expect(obtained.RatingsCount).To(Equal(int64(1)))
 		}
 	})
 
@@ -192,4 +193,30 @@ var _ = Describe("RecipesService", func() {
 			Expect(res.StatusCode).To(Equal(http.StatusOK))
 		}
 	})
+
+	It("should not find the deleted recipe", func() {
+		req := CreateHTTPRequest("GET", baseUrl+"/recipes/"+recipeID, nil)
+		client := &http.Client{Timeout: time.Duration(timeout)}
+		res, err := client.Do(req)
+
+		if err != nil {
+			GinkgoWriter.Write([]byte(err.Error()))
+			Expect(err).NotTo(HaveOccurred())
+		} else {
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		}
+	})
+
+	It("should reject a malformed recipe id", func() {
+		req := CreateHTTPRequest("DELETE", baseUrl+"/recipes/not-an-id", nil)
+		client := &http.Client{Timeout: time.Duration(timeout)}
+		res, err := client.Do(req)
+
+		if err != nil {
+			GinkgoWriter.Write([]byte(err.Error()))
+			Expect(err).NotTo(HaveOccurred())
+		} else {
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+		}
+	})
 })
@@ -0,0 +1,203 @@
+package recipes_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService search", func() {
+	ts := serveEach(nil)
+
+	type hit struct {
+		recipe.Recipe
+		Score float64 `json:"score"`
+	}
+	type result struct {
+		Recipes     []hit    `json:"recipes"`
+		Total       int64    `json:"total"`
+		Offset      int      `json:"offset"`
+		Limit       int      `json:"limit"`
+		Suggestions []string `json:"suggestions"`
+	}
+
+	search := func(text, params string) (*http.Response, result) {
+		u := ts.URL + "/recipes/search?q=" + url.QueryEscape(text)
+		if params != "" {
+			u += "&" + params
+		}
+		obtained := result{}
+		res := doJSON("GET", u, "", &obtained)
+		return res, obtained
+	}
+
+	names := func(r result) []string {
+		var names []string
+		for _, h := range r.Recipes {
+			names = append(names, h.Name)
+		}
+		return names
+	}
+
+	Describe("by name", func() {
+		BeforeEach(func() {
+			entries := []string{"Tomato Soup", "Soup of the Day", "Grilled Tomatoes", "Mushroom Soup (vegan)", "Pasta", "Chicken Lasagna"}
+			for _, name := range entries {
+				Expect(ts.storage.Store(context.Background(), &recipe.Recipe{Name: name, PrepTime: recipe.Duration(10 * time.Minute)})).To(Succeed())
+			}
+		})
+
+		It("should find the names containing the text, case-insensitive", func() {
+			res, obtained := search("SOUP", "")
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(obtained.Total).To(Equal(int64(3)))
+			// The closer to the beginning of the name the better
+			Expect(names(obtained)).To(Equal([]string{"Soup of the Day", "Tomato Soup", "Mushroom Soup (vegan)"}))
+			Expect(obtained.Recipes[0].Score).To(BeNumerically(">", obtained.Recipes[1].Score))
+		})
+
+		It("should treat the text literally", func() {
+			_, obtained := search("(vegan)", "")
+			Expect(names(obtained)).To(Equal([]string{"Mushroom Soup (vegan)"}))
+
+			_, obtained = search(".*", "")
+			Expect(obtained.Recipes).To(BeEmpty())
+		})
+
+		It("should find the words starting with the text", func() {
+			_, obtained := search("tom", "mode=prefix")
+			Expect(names(obtained)).To(ConsistOf("Tomato Soup", "Grilled Tomatoes"))
+
+			_, obtained = search("oup", "mode=prefix")
+			Expect(obtained.Recipes).To(BeEmpty())
+		})
+
+		It("should rank the full-text matches", func() {
+			_, obtained := search("tomato soup", "mode=fulltext")
+			Expect(obtained.Recipes).NotTo(BeEmpty())
+			Expect(obtained.Recipes[0].Name).To(Equal("Tomato Soup"))
+		})
+
+		It("should run regular expressions only when asked", func() {
+			_, obtained := search("^(Soup|Pasta)", "mode=regex")
+			Expect(names(obtained)).To(Equal([]string{"Pasta", "Soup of the Day"}))
+
+			res, _ := search("(", "mode=regex")
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		})
+
+		It("should page the results", func() {
+			_, obtained := search("soup", "offset=1&limit=1")
+			Expect(obtained.Total).To(Equal(int64(3)))
+			Expect(obtained.Offset).To(Equal(1))
+			Expect(obtained.Limit).To(Equal(1))
+			Expect(names(obtained)).To(Equal([]string{"Tomato Soup"}))
+		})
+
+		It("should keep the array of the recipes on the search path", func() {
+			obtained := []hit{}
+			res := doJSON("GET", ts.URL+"/recipes/search/soup?offset=1&limit=1", "", &obtained)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("X-Total-Count")).To(Equal("3"))
+			Expect(obtained).To(HaveLen(1))
+			Expect(obtained[0].Name).To(Equal("Tomato Soup"))
+		})
+
+		It("should tolerate typos when asked", func() {
+			_, obtained := search("lasagana", "")
+			Expect(obtained.Recipes).To(BeEmpty())
+
+			_, obtained = search("lasagana", "fuzzy=auto")
+			Expect(names(obtained)).To(Equal([]string{"Chicken Lasagna"}))
+
+			_, obtained = search("chiken lasagna", "fuzzy=1")
+			Expect(names(obtained)).To(Equal([]string{"Chicken Lasagna"}))
+
+			_, obtained = search("chiken", "mode=fulltext&fuzzy=true")
+			Expect(names(obtained)).To(Equal([]string{"Chicken Lasagna"}))
+		})
+
+		It("should suggest the closest words when nothing is found", func() {
+			_, obtained := search("chiken", "")
+			Expect(obtained.Recipes).To(BeEmpty())
+			Expect(obtained.Suggestions).To(Equal([]string{"chicken"}))
+
+			_, obtained = search("soup", "")
+			Expect(obtained.Suggestions).To(BeEmpty())
+		})
+	})
+
+	Describe("query language", func() {
+		BeforeEach(func() {
+			entries := []recipe.Recipe{
+				{Name: "Chicken Curry", PrepTime: recipe.Duration(45 * time.Minute), Difficulty: recipe.Normal, AverageRating: 4.5},
+				{Name: "Chicken Salad", PrepTime: recipe.Duration(15 * time.Minute), Difficulty: recipe.Easy, AverageRating: 4.2},
+				{Name: "Grilled Chicken", PrepTime: recipe.Duration(25 * time.Minute), Difficulty: recipe.Easy, AverageRating: 3.1},
+				{Name: "Veggie Curry", PrepTime: recipe.Duration(20 * time.Minute), Difficulty: recipe.Easy, Vegetarian: true, AverageRating: 4.8},
+			}
+			for i := range entries {
+				Expect(ts.storage.Store(context.Background(), &entries[i])).To(Succeed())
+			}
+		})
+
+		query := func(text string) []string {
+			res, obtained := search(text, "mode=query")
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			return names(obtained)
+		}
+
+		It("should combine the text and the field filters", func() {
+			Expect(query("chicken difficulty:easy vegetarian:false rating>=4 prep<30m")).
+				To(Equal([]string{"Chicken Salad"}))
+			Expect(query("prep<=PT25M rating<4")).To(Equal([]string{"Grilled Chicken"}))
+			Expect(query(`name:"chicken curry"`)).To(Equal([]string{"Chicken Curry"}))
+		})
+
+		It("should support negation, OR and grouping", func() {
+			Expect(query("curry -chicken")).To(Equal([]string{"Veggie Curry"}))
+			Expect(query("chicken NOT difficulty:easy")).To(Equal([]string{"Chicken Curry"}))
+			Expect(query("salad OR vegetarian:true")).To(Equal([]string{"Chicken Salad", "Veggie Curry"}))
+			Expect(query("(salad OR grilled) AND rating>4")).To(Equal([]string{"Chicken Salad"}))
+			Expect(query("-(chicken OR veggie)")).To(BeEmpty())
+		})
+
+		It("should report the position of the syntax errors", func() {
+			for text, pos := range map[string]float64{
+				"chicken colour:red":     8,
+				"rating>=high":           8,
+				"(chicken OR":            11,
+				"chicken)":               7,
+				`name:"chicken`:          5,
+				"curry OR OR veggie":     9,
+				"difficulty:impossible ": 11,
+			} {
+				obtained := map[string]interface{}{}
+				res := doJSON("GET", ts.URL+"/recipes/search/"+url.PathEscape(text)+"?mode=query", "", &obtained)
+				Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity), text)
+				Expect(obtained["position"]).To(Equal(pos), text)
+			}
+		})
+	})
+
+	It("should reject invalid queries", func() {
+		res, _ := search("soup", "mode=sql")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		res, _ = search("soup", "limit=1000")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		res, _ = search("soup", "fuzzy=3")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		res, _ = search("soup", "mode=regex&fuzzy=1")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		res, _ = search("soup", "offset=x")
+		Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
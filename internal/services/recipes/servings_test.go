@@ -0,0 +1,58 @@
+package recipes_test
+
+import (
+	"net/http"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService servings", func() {
+	ts := serveEach(nil)
+	var id string
+
+	BeforeEach(func() {
+		res, created := doRecipe("POST", ts.URL+"/recipes", `{
+			"name": "Shakshuka",
+			"difficulty": 2,
+			"servings": 2,
+			"ingredients": [
+				{"name": "eggs", "quantity": 4},
+				{"name": "tomatoes", "quantity": 400, "unit": "g"},
+				{"name": "cumin", "quantity": 1, "unit": "tsp"}
+			]
+		}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		id = created.IDHex()
+	})
+
+	It("should scale the ingredients to the servings", func() {
+		res, obtained := doRecipe("GET", ts.URL+"/recipes/"+id+"?servings=3", "")
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(obtained.Servings).To(Equal(3))
+		Expect(obtained.Ingredients).To(Equal([]recipe.Ingredient{
+			{Name: "eggs", Quantity: 6},
+			{Name: "tomatoes", Quantity: 600, Unit: "g"},
+			{Name: "cumin", Quantity: 1.5, Unit: "tsp"},
+		}))
+
+		_, obtained = doRecipe("GET", ts.URL+"/recipes/"+id+"?servings=1", "")
+		Expect(obtained.Ingredients[0].Quantity).To(Equal(2.0))
+
+		_, obtained = doRecipe("GET", ts.URL+"/recipes/"+id, "")
+		Expect(obtained.Servings).To(Equal(2))
+		Expect(obtained.Ingredients[0].Quantity).To(Equal(4.0))
+	})
+
+	It("should reject the invalid servings", func() {
+		res, _ := doRecipe("GET", ts.URL+"/recipes/"+id+"?servings=two", "")
+		Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+		res, _ = doRecipe("GET", ts.URL+"/recipes/"+id+"?servings=1000", "")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		res, _ = doRecipe("POST", ts.URL+"/recipes", `{"name": "Shakshuka", "difficulty": 1, "servings": -1}`)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
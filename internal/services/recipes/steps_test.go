@@ -0,0 +1,63 @@
+package recipes_test
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService steps", func() {
+	ts := serveEach(nil)
+
+	const ingredients = `[{"name": "spaghetti", "quantity": 400, "unit": "g"}, {"name": "salt", "notes": "to taste"}]`
+
+	It("should store and return the steps in order", func() {
+		res, created := doRecipe("POST", ts.URL+"/recipes", `{
+			"name": "Spaghetti",
+			"difficulty": 2,
+			"ingredients": `+ingredients+`,
+			"steps": [
+				{"text": "Bring the water to the boil", "duration": "PT10M", "ingredients": ["salt"]},
+				{"text": "Cook the spaghetti", "duration": "PT8M", "ingredients": ["Spaghetti"]},
+				{"text": "Serve"}
+			]
+		}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+
+		_, obtained := doRecipe("GET", ts.URL+"/recipes/"+created.IDHex(), "")
+		Expect(obtained.Steps).To(Equal([]recipe.Step{
+			{Text: "Bring the water to the boil", Duration: recipe.Duration(10 * time.Minute), Ingredients: []string{"salt"}},
+			{Text: "Cook the spaghetti", Duration: recipe.Duration(8 * time.Minute), Ingredients: []string{"Spaghetti"}},
+			{Text: "Serve"},
+		}))
+
+		res, _ = doRecipe("PUT", ts.URL+"/recipes/"+created.IDHex(),
+			`{"name": "Spaghetti", "difficulty": 1, "ingredients": `+ingredients+`, "steps": [{"text": "Cook", "ingredients": ["spaghetti", "salt"]}]}`)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		_, obtained = doRecipe("GET", ts.URL+"/recipes/"+created.IDHex(), "")
+		Expect(obtained.Steps).To(Equal([]recipe.Step{{Text: "Cook", Ingredients: []string{"spaghetti", "salt"}}}))
+	})
+
+	It("should validate the steps", func() {
+		for _, steps := range []string{
+			`[{"text": " "}]`,
+			`[{"text": "Cook", "ingredients": ["pepper"]}]`,
+		} {
+			res, _ := doRecipe("POST", ts.URL+"/recipes", `{"name": "Spaghetti", "difficulty": 1, "ingredients": `+ingredients+`, "steps": `+steps+`}`)
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity), steps)
+		}
+		res, _ := doRecipe("POST", ts.URL+"/recipes", `{"name": "Spaghetti", "difficulty": 1, "steps": [{"text": "Cook", "duration": "10 minutes"}]}`)
+		Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+
+		// The ingredients referenced by the steps cannot be dropped
+		res, created := doRecipe("POST", ts.URL+"/recipes",
+			`{"name": "Spaghetti", "difficulty": 1, "ingredients": `+ingredients+`, "steps": [{"text": "Salt", "ingredients": ["salt"]}]}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		res, _ = doRecipe("PUT", ts.URL+"/recipes/"+created.IDHex(),
+			`{"name": "Spaghetti", "difficulty": 1, "steps": [{"text": "Salt", "ingredients": ["salt"]}]}`)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
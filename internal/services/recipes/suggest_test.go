@@ -0,0 +1,77 @@
+package recipes_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService suggestions", func() {
+	ts := serveEach(nil)
+
+	suggest := func(params string) (*http.Response, []map[string]interface{}) {
+		var obtained []map[string]interface{}
+		res := doJSON("GET", ts.URL+"/recipes/suggest?"+params, "", &obtained)
+		return res, obtained
+	}
+
+	names := func(suggestions []map[string]interface{}) []interface{} {
+		var names []interface{}
+		for _, s := range suggestions {
+			names = append(names, s["name"])
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		entries := []recipe.Recipe{
+			{Name: "Chicken Curry", AverageRating: 4.0, RatingsCount: 100},
+			{Name: "Chickpea Salad", AverageRating: 5.0, RatingsCount: 2},
+			{Name: "Roast Chicken", AverageRating: 4.5, RatingsCount: 40},
+			{Name: "Beef Stew", AverageRating: 5.0, RatingsCount: 500},
+		}
+		for i := range entries {
+			Expect(ts.storage.Store(context.Background(), &entries[i])).To(Succeed())
+		}
+	})
+
+	It("should suggest the popular names by the beginning of the words", func() {
+		res, obtained := suggest("q=CHIC")
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(names(obtained)).To(Equal([]interface{}{"Chicken Curry", "Roast Chicken", "Chickpea Salad"}))
+		// Only the ID and the name are returned
+		Expect(obtained[0]).To(HaveLen(2))
+		Expect(obtained[0]["id"]).NotTo(BeEmpty())
+	})
+
+	It("should match every word and honor the limit", func() {
+		_, obtained := suggest("q=roa+chi")
+		Expect(names(obtained)).To(Equal([]interface{}{"Roast Chicken"}))
+
+		_, obtained = suggest("q=chic&limit=1")
+		Expect(names(obtained)).To(Equal([]interface{}{"Chicken Curry"}))
+
+		_, obtained = suggest("q=hicken")
+		Expect(obtained).To(BeEmpty())
+	})
+
+	It("should follow the ratings", func() {
+		_, obtained := suggest("q=chic")
+		for i := 0; i < 50; i++ {
+			Expect(ts.storage.ApplyRating(context.Background(), obtained[2]["id"].(string), 5)).To(Succeed())
+		}
+		_, obtained = suggest("q=chic")
+		Expect(obtained[0]["name"]).To(Equal("Chickpea Salad"))
+	})
+
+	It("should reject invalid queries", func() {
+		res, _ := suggest("q=")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		res, _ = suggest("q=chic&limit=1000")
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
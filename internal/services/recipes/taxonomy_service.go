@@ -0,0 +1,92 @@
+package recipes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ashkarin/ashkarin-api-test/internal/utils"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/usecases"
+)
+
+// termKind returns the kind of the terms of the route
+func termKind(r *http.Request) recipe.TermKind {
+	kind, _ := recipe.ParseTermKind(mux.Vars(r)["kind"])
+	return kind
+}
+
+// ListTerms is the HTTP handler to list the taxonomy terms of the kind
+// with the numbers of their recipes
+func (s *Service) ListTerms(w http.ResponseWriter, r *http.Request) {
+	terms, err := usecases.ListTerms(r.Context(), s.storage, termKind(r))
+	if err != nil {
+		log.Errorf("ListTerms: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusOK, terms)
+}
+
+// CreateTerm is the HTTP handler to create the taxonomy term
+func (s *Service) CreateTerm(w http.ResponseWriter, r *http.Request) {
+	var term recipe.Term
+	if err := json.NewDecoder(r.Body).Decode(&term); err != nil {
+		log.Errorf("CreateTerm: %v", err)
+		utils.ResponseWithError(w, http.StatusBadRequest, "Invalid request payload JSON format")
+		return
+	}
+	defer r.Body.Close()
+
+	term.Kind = termKind(r)
+	if err := usecases.CreateTerm(r.Context(), s.storage, &term); err != nil {
+		log.Errorf("CreateTerm: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusCreated, term)
+}
+
+// GetTerm is the HTTP handler to get the taxonomy term by slug
+func (s *Service) GetTerm(w http.ResponseWriter, r *http.Request) {
+	term, err := usecases.GetTerm(r.Context(), s.storage, termKind(r), mux.Vars(r)["slug"])
+	if err != nil {
+		log.Errorf("GetTerm: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusOK, term)
+}
+
+// UpdateTerm is the HTTP handler to update the taxonomy term. The changed
+// slug renames the term in all the recipes
+func (s *Service) UpdateTerm(w http.ResponseWriter, r *http.Request) {
+	var term recipe.Term
+	if err := json.NewDecoder(r.Body).Decode(&term); err != nil {
+		log.Errorf("UpdateTerm: %v", err)
+		utils.ResponseWithError(w, http.StatusBadRequest, "Invalid request payload JSON format")
+		return
+	}
+	defer r.Body.Close()
+
+	term.Kind = termKind(r)
+	if err := usecases.UpdateTerm(r.Context(), s.storage, mux.Vars(r)["slug"], &term); err != nil {
+		log.Errorf("UpdateTerm: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusOK, term)
+}
+
+// DeleteTerm is the HTTP handler to delete the taxonomy term, it is
+// removed from all the recipes
+func (s *Service) DeleteTerm(w http.ResponseWriter, r *http.Request) {
+	if err := usecases.DeleteTerm(r.Context(), s.storage, termKind(r), mux.Vars(r)["slug"]); err != nil {
+		log.Errorf("DeleteTerm: %v", err)
+		responseWithRecipeError(w, err)
+		return
+	}
+	utils.ResponseWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
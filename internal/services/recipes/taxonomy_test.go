@@ -0,0 +1,149 @@
+package recipes_test
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService taxonomy", func() {
+	ts := serveEach(nil)
+	var ids map[string]string
+
+	list := func(params string) []string {
+		obtained := struct {
+			Recipes []recipe.Recipe `json:"recipes"`
+		}{}
+		res := doJSON("GET", ts.URL+"/recipes?sort=name&"+params, "", &obtained)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		var names []string
+		for _, r := range obtained.Recipes {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+
+	counts := func(kind string) map[string]int64 {
+		var terms []recipe.Term
+		Expect(doJSON("GET", ts.URL+"/"+kind, "", &terms).StatusCode).To(Equal(http.StatusOK))
+		counts := map[string]int64{}
+		for _, t := range terms {
+			counts[t.Slug] = t.Count
+		}
+		return counts
+	}
+
+	BeforeEach(func() {
+		for _, term := range []struct{ kind, body string }{
+			{"cuisines", `{"name": "Italian"}`},
+			{"cuisines", `{"name": "Thai"}`},
+			{"meals", `{"name": "Dinner"}`},
+			{"tags", `{"name": "One Pot", "description": "Cooked in a single pot"}`},
+			{"tags", `{"name": "Kid-friendly", "slug": "kids"}`},
+		} {
+			Expect(doJSON("POST", ts.URL+"/"+term.kind, term.body, nil).StatusCode).To(Equal(http.StatusCreated))
+		}
+
+		ids = map[string]string{}
+		for name, body := range map[string]string{
+			"Risotto":     `{"name": "Risotto", "difficulty": 1, "cuisines": ["italian"], "meals": ["dinner"], "tags": ["One Pot", "kids"]}`,
+			"Lasagna":     `{"name": "Lasagna", "difficulty": 1, "cuisines": ["italian"], "tags": ["kids"]}`,
+			"Green Curry": `{"name": "Green Curry", "difficulty": 1, "cuisines": ["thai"], "meals": ["dinner"], "tags": ["one-pot"]}`,
+		} {
+			created := recipe.Recipe{}
+			Expect(doJSON("POST", ts.URL+"/recipes", body, &created).StatusCode).To(Equal(http.StatusCreated))
+			ids[name] = created.IDHex()
+		}
+	})
+
+	It("should manage the terms", func() {
+		term := recipe.Term{}
+		Expect(doJSON("GET", ts.URL+"/tags/one-pot", "", &term).StatusCode).To(Equal(http.StatusOK))
+		Expect(term).To(Equal(recipe.Term{
+			Kind: recipe.KindTag, Slug: "one-pot", Name: "One Pot", Description: "Cooked in a single pot", Count: 2,
+		}))
+
+		var terms []recipe.Term
+		doJSON("GET", ts.URL+"/cuisines", "", &terms)
+		Expect(terms).To(HaveLen(2))
+		Expect(terms[0].Name).To(Equal("Italian"))
+		Expect(counts("cuisines")).To(Equal(map[string]int64{"italian": 2, "thai": 1}))
+		Expect(counts("tags")).To(Equal(map[string]int64{"one-pot": 2, "kids": 2}))
+
+		Expect(doJSON("PUT", ts.URL+"/meals/dinner", `{"name": "Dinner", "description": "Evening meal"}`, &term).StatusCode).
+			To(Equal(http.StatusOK))
+		Expect(term.Description).To(Equal("Evening meal"))
+		Expect(term.Count).To(Equal(int64(2)))
+
+		Expect(doJSON("GET", ts.URL+"/tags/missing", "", nil).StatusCode).To(Equal(http.StatusNotFound))
+		Expect(doJSON("PUT", ts.URL+"/tags/missing", `{"name": "Missing"}`, nil).StatusCode).To(Equal(http.StatusNotFound))
+		Expect(doJSON("DELETE", ts.URL+"/tags/missing", "", nil).StatusCode).To(Equal(http.StatusNotFound))
+		Expect(doJSON("GET", ts.URL+"/colours", "", nil).StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("should reject the invalid and duplicate terms", func() {
+		Expect(doJSON("POST", ts.URL+"/tags", `{"name": "one pot"}`, nil).StatusCode).To(Equal(http.StatusConflict))
+		Expect(doJSON("POST", ts.URL+"/tags", `{"name": " "}`, nil).StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(doJSON("POST", ts.URL+"/tags", `{"name": "!!!"}`, nil).StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(doJSON("POST", ts.URL+"/tags", `{"name":`, nil).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(doJSON("PUT", ts.URL+"/tags/kids", `{"name": "One Pot"}`, nil).StatusCode).To(Equal(http.StatusConflict))
+		// The same slug of another kind is fine
+		Expect(doJSON("POST", ts.URL+"/meals", `{"name": "One Pot"}`, nil).StatusCode).To(Equal(http.StatusCreated))
+	})
+
+	It("should accept only the existing terms in the recipes", func() {
+		res := doJSON("POST", ts.URL+"/recipes", `{"name": "Pad Thai", "difficulty": 1, "cuisines": ["thai"], "tags": ["spicy"]}`, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		res = doJSON("PUT", ts.URL+"/recipes/"+ids["Lasagna"], `{"name": "Lasagna", "difficulty": 1, "meals": ["brunch"]}`, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+		// The terms are given by slug or by name, the duplicates are dropped
+		obtained := recipe.Recipe{}
+		doJSON("GET", ts.URL+"/recipes/"+ids["Risotto"], "", &obtained)
+		Expect(obtained.Tags).To(Equal([]string{"one-pot", "kids"}))
+	})
+
+	It("should filter the recipes by the terms", func() {
+		Expect(list("cuisine=italian")).To(Equal([]string{"Lasagna", "Risotto"}))
+		Expect(list("cuisine=italian,thai&meal=dinner")).To(Equal([]string{"Green Curry", "Risotto"}))
+		Expect(list("tag=one-pot,kids")).To(Equal([]string{"Risotto"}))
+		Expect(list("tag=One+Pot")).To(Equal([]string{"Green Curry", "Risotto"}))
+		Expect(list("tag=kids&cuisine=thai")).To(BeEmpty())
+	})
+
+	It("should rename the term in all the recipes", func() {
+		before := recipe.Recipe{}
+		doJSON("GET", ts.URL+"/recipes/"+ids["Green Curry"], "", &before)
+
+		term := recipe.Term{}
+		res := doJSON("PUT", ts.URL+"/tags/one-pot", `{"name": "One Pan"}`, &term)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(term.Slug).To(Equal("one-pan"))
+		Expect(term.Count).To(Equal(int64(2)))
+
+		Expect(list("tag=one-pan")).To(Equal([]string{"Green Curry", "Risotto"}))
+		Expect(doJSON("GET", ts.URL+"/tags/one-pot", "", nil).StatusCode).To(Equal(http.StatusNotFound))
+
+		after := recipe.Recipe{}
+		doJSON("GET", ts.URL+"/recipes/"+ids["Green Curry"], "", &after)
+		Expect(after.Tags).To(Equal([]string{"one-pan"}))
+		Expect(after.Version).To(Equal(before.Version + 1))
+
+		res = doJSON("PUT", ts.URL+"/recipes/"+ids["Green Curry"],
+			`{"name": "Green Curry", "difficulty": 1, "version": `+strconv.FormatInt(before.Version, 10)+`}`, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("should remove the deleted term from the recipes", func() {
+		Expect(doJSON("DELETE", ts.URL+"/tags/kids", "", nil).StatusCode).To(Equal(http.StatusOK))
+		Expect(counts("tags")).To(Equal(map[string]int64{"one-pot": 2}))
+
+		obtained := recipe.Recipe{}
+		doJSON("GET", ts.URL+"/recipes/"+ids["Lasagna"], "", &obtained)
+		Expect(obtained.Tags).To(BeEmpty())
+		Expect(obtained.Cuisines).To(Equal([]string{"italian"}))
+	})
+})
@@ -0,0 +1,100 @@
+package recipes_test
+
+import (
+	"net/http"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService units", func() {
+	ts := serveEach(nil)
+	var id string
+
+	BeforeEach(func() {
+		res, created := doRecipe("POST", ts.URL+"/recipes", `{
+			"name": "Pound Cake",
+			"difficulty": 2,
+			"servings": 8,
+			"ingredients": [
+				{"name": "butter", "quantity": 1, "unit": "lb"},
+				{"name": "flour", "quantity": 2, "unit": "cups"},
+				{"name": "vanilla extract", "quantity": 1, "unit": "tsp"},
+				{"name": "eggs", "quantity": 4}
+			],
+			"steps": [{"text": "Bake at 325°F for an hour"}]
+		}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		id = created.IDHex()
+	})
+
+	metric := []recipe.Ingredient{
+		{Name: "butter", Quantity: 455, Unit: "g"},
+		{Name: "flour", Quantity: 250, Unit: "g"},
+		{Name: "vanilla extract", Quantity: 1, Unit: "tsp"},
+		{Name: "eggs", Quantity: 4},
+	}
+
+	It("should convert the recipe to the metric units", func() {
+		obtained := recipe.Recipe{}
+		res := doJSON("GET", ts.URL+"/recipes/"+id+"?units=metric", "", &obtained)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(obtained.Ingredients).To(Equal(metric))
+		Expect(obtained.Steps[0].Text).To(Equal("Bake at 163°C for an hour"))
+
+		// The stored recipe keeps its units
+		obtained = recipe.Recipe{}
+		doJSON("GET", ts.URL+"/recipes/"+id, "", &obtained)
+		Expect(obtained.Ingredients[1]).To(Equal(recipe.Ingredient{Name: "flour", Quantity: 2, Unit: "cups"}))
+		Expect(obtained.Steps[0].Text).To(Equal("Bake at 325°F for an hour"))
+	})
+
+	It("should combine the conversion with the servings", func() {
+		obtained := recipe.Recipe{}
+		doJSON("GET", ts.URL+"/recipes/"+id+"?servings=4&units=metric", "", &obtained)
+		Expect(obtained.Ingredients[1]).To(Equal(recipe.Ingredient{Name: "flour", Quantity: 125, Unit: "g"}))
+	})
+
+	It("should convert the lists and the search results", func() {
+		page := struct {
+			Recipes []recipe.Recipe `json:"recipes"`
+		}{}
+		doJSON("GET", ts.URL+"/recipes?units=metric", "", &page)
+		Expect(page.Recipes).To(HaveLen(1))
+		Expect(page.Recipes[0].Ingredients).To(Equal(metric))
+
+		page.Recipes = nil
+		doJSON("GET", ts.URL+"/recipes/search?q=cake&units=metric", "", &page)
+		Expect(page.Recipes).To(HaveLen(1))
+		Expect(page.Recipes[0].Ingredients).To(Equal(metric))
+	})
+
+	It("should not convert the ingredients as the temperatures", func() {
+		res, created := doRecipe("POST", ts.URL+"/recipes", `{
+			"name": "Bread",
+			"difficulty": 1,
+			"ingredients": [
+				{"name": "flour", "quantity": 1, "unit": "c"},
+				{"name": "water", "quantity": 350, "unit": "f"},
+				{"name": "salt", "quantity": 1, "unit": "°C"}
+			]
+		}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+
+		for _, sys := range []string{"metric", "imperial"} {
+			obtained := recipe.Recipe{}
+			doJSON("GET", ts.URL+"/recipes/"+created.IDHex()+"?units="+sys, "", &obtained)
+			Expect(obtained.Ingredients).To(Equal([]recipe.Ingredient{
+				{Name: "flour", Quantity: 1, Unit: "c"},
+				{Name: "water", Quantity: 350, Unit: "f"},
+				{Name: "salt", Quantity: 1, Unit: "°C"},
+			}), sys)
+		}
+	})
+
+	It("should reject the unknown systems", func() {
+		res := doJSON("GET", ts.URL+"/recipes/"+id+"?units=klingon", "", &recipe.Recipe{})
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
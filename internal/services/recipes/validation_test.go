@@ -0,0 +1,101 @@
+package recipes_test
+
+import (
+	"net/http"
+
+	"github.com/ashkarin/ashkarin-api-test/internal/services/recipes"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	"github.com/gorilla/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService validation", func() {
+	ts := serveEach(func(router *mux.Router) recipe.StorageGateway {
+		storage := gateways.NewMemoryGateway()
+		_ = recipes.NewImportService(storage, "secret", router)
+		return storage
+	})
+
+	type response struct {
+		recipe.Recipe
+		Errors []recipe.FieldError `json:"errors"`
+	}
+
+	do := func(method, url, token, body string) (*http.Response, response) {
+		req := CreateHTTPRequest(method, url, body)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		obtained := response{}
+		res := doRequest(req, &obtained)
+		return res, obtained
+	}
+
+	It("should list the errors of all the invalid fields", func() {
+		res, obtained := do("POST", ts.URL+"/recipes", "", `{
+			"name": " ",
+			"difficulty": 99,
+			"servings": -1,
+			"ingredients": [{"name": "flour"}, {"name": ""}],
+			"steps": [{"text": "Mix", "ingredients": ["sugar"]}]
+		}`)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(obtained.Errors).To(Equal([]recipe.FieldError{
+			{Field: "name", Message: "is required"},
+			{Field: "difficulty", Message: "must be from 1 to 3"},
+			{Field: "servings", Message: "must be from 0 to 100"},
+			{Field: "ingredients[1]", Message: "name is required"},
+			{Field: "steps[0]", Message: `unknown ingredient "sugar"`},
+		}))
+
+		res, obtained = do("POST", ts.URL+"/recipes", "", `{"name": "Soup"}`)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(obtained.Errors).To(Equal([]recipe.FieldError{{Field: "difficulty", Message: "must be from 1 to 3"}}))
+	})
+
+	It("should trim the name", func() {
+		res, created := do("POST", ts.URL+"/recipes", "", `{"name": "  Soup ", "difficulty": 1}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		Expect(created.Name).To(Equal("Soup"))
+
+		r := &recipe.Recipe{Name: "  Soup ", Difficulty: recipe.Easy}
+		Expect(r.Validate()).To(Succeed())
+		Expect(r.Name).To(Equal("  Soup "))
+	})
+
+	It("should ignore the given ratings", func() {
+		res, created := do("POST", ts.URL+"/recipes", "", `{"name": "Soup", "difficulty": 1, "averageRating": 7, "ratingsCount": -3}`)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		Expect(created.AverageRating).To(BeZero())
+		Expect(created.RatingsCount).To(BeZero())
+
+		do("POST", ts.URL+"/recipes/"+created.IDHex()+"/rate/4", "", "")
+		res, updated := do("PUT", ts.URL+"/recipes/"+created.IDHex(), "", `{"name": "Soup", "difficulty": 2, "averageRating": 1, "ratingsCount": 50}`)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(updated.AverageRating).To(Equal(4.0))
+		Expect(updated.RatingsCount).To(Equal(int64(1)))
+	})
+
+	It("should import the recipes with the ratings", func() {
+		body := `{"name": "Soup", "difficulty": 1, "averageRating": 4.5, "ratingsCount": 12}`
+		res, _ := do("POST", ts.URL+"/recipes/import", "", body)
+		Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		res, _ = do("POST", ts.URL+"/recipes/import", "wrong", body)
+		Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		res, imported := do("POST", ts.URL+"/recipes/import", "secret", body)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		Expect(imported.AverageRating).To(Equal(4.5))
+		Expect(imported.RatingsCount).To(Equal(int64(12)))
+
+		res, obtained := do("POST", ts.URL+"/recipes/import", "secret",
+			`{"name": "Soup", "difficulty": 1, "averageRating": 5.5, "ratingsCount": -1}`)
+		Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(obtained.Errors).To(Equal([]recipe.FieldError{
+			{Field: "ratingsCount", Message: "cannot be negative"},
+			{Field: "averageRating", Message: "must be from 0 to 5"},
+		}))
+	})
+})
@@ -0,0 +1,85 @@
+package recipes_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RecipesService versions", func() {
+	ts := serveEach(nil)
+	var stored *recipe.Recipe
+
+	BeforeEach(func() {
+		stored = &recipe.Recipe{Name: "Versioned", PrepTime: recipe.Duration(20 * time.Minute), Difficulty: recipe.Easy, Servings: 2}
+		Expect(ts.storage.Store(context.Background(), stored)).To(Succeed())
+	})
+
+	It("should return the ETag of the recipe", func() {
+		res := doJSON("GET", ts.URL+"/recipes/"+stored.IDHex(), "", nil)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.Header.Get("ETag")).To(Equal(`"1"`))
+
+		req := CreateHTTPRequest("GET", ts.URL+"/recipes/"+stored.IDHex(), nil)
+		req.Header.Set("If-None-Match", `"1"`)
+		res = doRequest(req, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusNotModified))
+	})
+
+	It("should tell the representations apart by the ETag", func() {
+		get := func(query, match string) *http.Response {
+			req := CreateHTTPRequest("GET", ts.URL+"/recipes/"+stored.IDHex()+query, nil)
+			if match != "" {
+				req.Header.Set("If-None-Match", match)
+			}
+			return doRequest(req, nil)
+		}
+
+		Expect(get("?servings=4", "").Header.Get("ETag")).To(Equal(`"1-s4"`))
+		Expect(get("?units=imperial", "").Header.Get("ETag")).To(Equal(`"1-imperial"`))
+		Expect(get("?servings=4&units=imperial", "").Header.Get("ETag")).To(Equal(`"1-s4-imperial"`))
+
+		Expect(get("?servings=4", `"1"`).StatusCode).To(Equal(http.StatusOK))
+		Expect(get("?servings=4", `"1-s4"`).StatusCode).To(Equal(http.StatusNotModified))
+		Expect(get("", `"1-s4"`).StatusCode).To(Equal(http.StatusOK))
+
+		// The variants are never written over the stored recipe
+		for _, method := range []string{"PUT", "PATCH"} {
+			req := CreateHTTPRequest(method, ts.URL+"/recipes/"+stored.IDHex(), `{"name": "Updated", "difficulty": 1}`)
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req.Header.Set("If-Match", `"1-s4-imperial"`)
+			Expect(doRequest(req, nil).StatusCode).To(Equal(http.StatusPreconditionFailed), method)
+		}
+		Expect(get("", `"1"`).StatusCode).To(Equal(http.StatusNotModified))
+	})
+
+	It("should update the recipe matching the If-Match header", func() {
+		req := CreateHTTPRequest("PUT", ts.URL+"/recipes/"+stored.IDHex(), `{"name": "Updated", "difficulty": 1}`)
+		req.Header.Set("If-Match", `"1"`)
+		res := doRequest(req, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.Header.Get("ETag")).To(Equal(`"2"`))
+	})
+
+	It("should reject the stale If-Match header", func() {
+		req := CreateHTTPRequest("PUT", ts.URL+"/recipes/"+stored.IDHex(), `{"name": "First", "difficulty": 1}`)
+		req.Header.Set("If-Match", `"1"`)
+		res := doRequest(req, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+		req = CreateHTTPRequest("PUT", ts.URL+"/recipes/"+stored.IDHex(), `{"name": "Second", "difficulty": 1}`)
+		req.Header.Set("If-Match", `"1"`)
+		res = doRequest(req, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusPreconditionFailed))
+	})
+
+	It("should reject the stale version in the payload", func() {
+		req := CreateHTTPRequest("PUT", ts.URL+"/recipes/"+stored.IDHex(), `{"name": "Stale", "difficulty": 1, "version": 7}`)
+		res := doRequest(req, nil)
+		Expect(res.StatusCode).To(Equal(http.StatusConflict))
+	})
+})
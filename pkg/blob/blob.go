@@ -0,0 +1,47 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when the blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for the keys which are not valid paths
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Store represent a storage of the binary objects, such as the images. The
+// keys are slash separated paths, e.g. recipes/<id>/<image>/card.jpg
+type Store interface {
+	// Put stores the blob read from r, it replaces the blob of the key
+	Put(ctx context.Context, key, contentType string, r io.Reader) error
+	// Get returns the blob with its content type, the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	// Delete deletes the blob, the missing blob is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the URL the blob is served by
+	URL(key string) string
+}
+
+// ValidateKey checks the key is a relative path of the letters, digits,
+// dots, dashes and underscores, without the empty, . and .. segments
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: empty key", ErrInvalidKey)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+		for _, c := range segment {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("._-", c)) {
+				return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package blob_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/blob"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileStore", func() {
+	var (
+		ctx   = context.Background()
+		dir   string
+		store blob.Store
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "blobs")
+		Expect(err).NotTo(HaveOccurred())
+		store, err = blob.NewFileStore(dir, "http://localhost/images")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should put, get and delete the blobs", func() {
+		key := "recipes/1/2/card.jpg"
+		Expect(store.Put(ctx, key, "image/jpeg", strings.NewReader("first"))).To(Succeed())
+		Expect(store.Put(ctx, key, "image/jpeg", strings.NewReader("second"))).To(Succeed())
+
+		r, contentType, err := store.Get(ctx, key)
+		Expect(err).NotTo(HaveOccurred())
+		data, _ := ioutil.ReadAll(r)
+		r.Close()
+		Expect(string(data)).To(Equal("second"))
+		Expect(contentType).To(Equal("image/jpeg"))
+		Expect(store.URL(key)).To(Equal("http://localhost/images/recipes/1/2/card.jpg"))
+
+		Expect(store.Delete(ctx, key)).To(Succeed())
+		Expect(store.Delete(ctx, key)).To(Succeed())
+		_, _, err = store.Get(ctx, key)
+		Expect(err).To(Equal(blob.ErrNotFound))
+		// The emptied directories are removed, the root is kept
+		Expect(filepath.Join(dir, "recipes")).NotTo(BeADirectory())
+		Expect(dir).To(BeADirectory())
+	})
+
+	It("should reject the keys out of the directory", func() {
+		for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", "a/./b", "a b", `a\b`} {
+			Expect(store.Put(ctx, key, "", strings.NewReader("x"))).To(MatchError(blob.ErrInvalidKey), key)
+			_, _, err := store.Get(ctx, key)
+			Expect(err).To(MatchError(blob.ErrInvalidKey), key)
+		}
+	})
+})
@@ -0,0 +1,172 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// Kind is the origin of the ingredient which the diet labels depend on
+type Kind string
+
+// Kinds of the ingredients. The animal kind is the other animal products,
+// such as honey, the fish kind includes the seafood
+const (
+	KindPlant  Kind = "plant"
+	KindDairy  Kind = "dairy"
+	KindEgg    Kind = "egg"
+	KindAnimal Kind = "animal"
+	KindFish   Kind = "fish"
+	KindMeat   Kind = "meat"
+)
+
+// kindDiets are the diet labels which the ingredients of the kind keep
+var kindDiets = map[Kind][]recipe.Diet{
+	KindPlant:  {recipe.DietVegan, recipe.DietVegetarian, recipe.DietPescatarian},
+	KindDairy:  {recipe.DietVegetarian, recipe.DietPescatarian},
+	KindEgg:    {recipe.DietVegetarian, recipe.DietPescatarian},
+	KindAnimal: {recipe.DietVegetarian, recipe.DietPescatarian},
+	KindFish:   {recipe.DietPescatarian},
+	KindMeat:   nil,
+}
+
+// Entry is the ingredient of the catalog. The kind is plant if it is empty
+type Entry struct {
+	Name      string            `json:"name"`
+	Kind      Kind              `json:"kind,omitempty"`
+	Allergens []recipe.Allergen `json:"allergens,omitempty"`
+}
+
+// validate checks the entry and sets its default kind
+func (e *Entry) validate() error {
+	if e.Kind == "" {
+		e.Kind = KindPlant
+	}
+	if _, ok := kindDiets[e.Kind]; !ok {
+		return fmt.Errorf("unknown kind %q", e.Kind)
+	}
+	for i, a := range e.Allergens {
+		allergen, err := recipe.ParseAllergen(string(a))
+		if err != nil {
+			return fmt.Errorf("unknown allergen %q", a)
+		}
+		e.Allergens[i] = allergen
+	}
+	return nil
+}
+
+// Catalog is the catalog of the ingredients
+type Catalog struct {
+	entries map[string]*Entry
+	keys    map[string]bool
+}
+
+// NewCatalog returns the catalog of the entries
+func NewCatalog(entries []*Entry) (*Catalog, error) {
+	c := &Catalog{entries: make(map[string]*Entry, len(entries)), keys: make(map[string]bool, len(entries))}
+	for i, e := range entries {
+		key := recipe.IngredientKey(e.Name)
+		switch {
+		case key == "":
+			return nil, fmt.Errorf("entry %d: name is required", i+1)
+		case c.entries[key] != nil:
+			return nil, fmt.Errorf("entry %d: duplicate name %q", i+1, e.Name)
+		}
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("entry %d: %v", i+1, err)
+		}
+		c.entries[key] = e
+		c.keys[key] = true
+	}
+	return c, nil
+}
+
+// Len returns the number of the entries
+func (c *Catalog) Len() int {
+	return len(c.entries)
+}
+
+// Lookup returns the entry of the ingredient, see recipe.MatchIngredient
+func (c *Catalog) Lookup(ingredient string) (*Entry, bool) {
+	key, ok := recipe.MatchIngredient(ingredient, c.keys)
+	if !ok {
+		return nil, false
+	}
+	return c.entries[key], true
+}
+
+// Load reads the catalog from the CSV or JSON file, by its extension
+func Load(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReadCSV(f)
+	case ".json":
+		return ReadJSON(f)
+	}
+	return nil, fmt.Errorf("unknown format of the ingredient catalog %s", path)
+}
+
+// ReadJSON reads the catalog from the JSON array of the entries
+func ReadJSON(r io.Reader) (*Catalog, error) {
+	var entries []*Entry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return NewCatalog(entries)
+}
+
+// ReadCSV reads the catalog from CSV with the header of the name, kind and
+// allergens columns in any order. The allergens are separated by
+// semicolons, the kind and allergens may be empty
+func ReadCSV(r io.Reader) (*Catalog, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"name", "kind", "allergens"} {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("column %s is missing", name)
+		}
+	}
+
+	var entries []*Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		e := &Entry{
+			Name: record[index["name"]],
+			Kind: Kind(strings.TrimSpace(record[index["kind"]])),
+		}
+		for _, a := range strings.Split(record[index["allergens"]], ";") {
+			if a = strings.TrimSpace(a); a != "" {
+				e.Allergens = append(e.Allergens, recipe.Allergen(a))
+			}
+		}
+		entries = append(entries, e)
+	}
+	return NewCatalog(entries)
+}
@@ -0,0 +1,155 @@
+package catalog_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/catalog"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const entries = `# kinds and allergens
+name,kind,allergens
+egg,egg,eggs
+flour,,gluten
+milk,dairy,milk
+almond milk,,nuts
+tomato,,
+honey,animal,
+salmon,fish,fish
+chicken,meat,
+soy sauce,,soybeans; gluten
+`
+
+var _ = Describe("Catalog", func() {
+	var c *catalog.Catalog
+
+	BeforeEach(func() {
+		var err error
+		c, err = catalog.ReadCSV(strings.NewReader(entries))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	classify := func(overrides *recipe.AllergenOverrides, names ...string) *recipe.Recipe {
+		r := &recipe.Recipe{AllergenOverrides: overrides}
+		for _, name := range names {
+			r.Ingredients = append(r.Ingredients, recipe.Ingredient{Name: name})
+		}
+		c.Classify(r)
+		return r
+	}
+
+	It("should read the catalogs", func() {
+		Expect(c.Len()).To(Equal(9))
+		e, ok := c.Lookup("Soy Sauce")
+		Expect(ok).To(BeTrue())
+		Expect(e.Kind).To(Equal(catalog.KindPlant))
+		Expect(e.Allergens).To(Equal([]recipe.Allergen{recipe.AllergenSoybeans, recipe.AllergenGluten}))
+		e, _ = c.Lookup("200 ml unsweetened almond milk")
+		Expect(e.Name).To(Equal("almond milk"))
+
+		fromJSON, err := catalog.ReadJSON(strings.NewReader(`[{"name": "prawn", "kind": "fish", "allergens": ["Crustaceans"]}]`))
+		Expect(err).NotTo(HaveOccurred())
+		e, _ = fromJSON.Lookup("prawns")
+		Expect(e.Allergens).To(Equal([]recipe.Allergen{recipe.AllergenCrustaceans}))
+
+		_, err = catalog.ReadCSV(strings.NewReader("name,kind\negg,egg\n"))
+		Expect(err).To(MatchError(ContainSubstring("column allergens")))
+		_, err = catalog.ReadCSV(strings.NewReader(entries + "tofu,bean,soybeans\n"))
+		Expect(err).To(MatchError(ContainSubstring("unknown kind")))
+		_, err = catalog.ReadCSV(strings.NewReader(entries + "peanut,,nuts;peanut\n"))
+		Expect(err).To(MatchError(ContainSubstring("unknown allergen")))
+		_, err = catalog.ReadCSV(strings.NewReader(entries + "Eggs,egg,eggs\n"))
+		Expect(err).To(MatchError(ContainSubstring("duplicate")))
+	})
+
+	It("should load the catalog of the configuration", func() {
+		loaded, err := catalog.Load("../../../configs/ingredients.csv")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Len()).To(BeNumerically(">", 50))
+	})
+
+	It("should derive the diets and allergens from the ingredients", func() {
+		r := classify(nil, "tomatoes", "almond milk")
+		Expect(r.Diets).To(Equal([]recipe.Diet{
+			recipe.DietVegan, recipe.DietVegetarian, recipe.DietPescatarian, recipe.DietGlutenFree, recipe.DietDairyFree,
+		}))
+		Expect(r.Allergens).To(Equal([]recipe.Allergen{recipe.AllergenNuts}))
+		Expect(r.Vegetarian).To(BeTrue())
+
+		r = classify(nil, "flour", "eggs", "milk", "honey")
+		Expect(r.Diets).To(Equal([]recipe.Diet{recipe.DietVegetarian, recipe.DietPescatarian, recipe.DietNutFree}))
+		Expect(r.Allergens).To(Equal([]recipe.Allergen{recipe.AllergenGluten, recipe.AllergenEggs, recipe.AllergenMilk}))
+
+		r = classify(nil, "salmon fillet", "soy sauce")
+		Expect(r.Diets).To(Equal([]recipe.Diet{recipe.DietPescatarian, recipe.DietDairyFree, recipe.DietNutFree}))
+		Expect(r.Vegetarian).To(BeFalse())
+
+		r = classify(nil, "chicken", "tomato")
+		Expect(r.Diets).To(Equal([]recipe.Diet{recipe.DietGlutenFree, recipe.DietDairyFree, recipe.DietNutFree}))
+	})
+
+	It("should apply the allergen overrides", func() {
+		r := classify(&recipe.AllergenOverrides{
+			Add:    []recipe.Allergen{recipe.AllergenSesame},
+			Remove: []recipe.Allergen{recipe.AllergenGluten},
+		}, "flour", "tomato")
+		Expect(r.Allergens).To(Equal([]recipe.Allergen{recipe.AllergenSesame}))
+		Expect(r.HasDiet(recipe.DietGlutenFree)).To(BeTrue())
+	})
+
+	It("should keep the given diets allowed by the known ingredients when an ingredient is unknown", func() {
+		given := func(overrides *recipe.AllergenOverrides, diets []recipe.Diet, names ...string) *recipe.Recipe {
+			r := &recipe.Recipe{Diets: diets, AllergenOverrides: overrides}
+			for _, name := range names {
+				r.Ingredients = append(r.Ingredients, recipe.Ingredient{Name: name})
+			}
+			c.Classify(r)
+			return r
+		}
+		vegan := []recipe.Diet{recipe.DietVegan}
+
+		r := given(nil, vegan, "seitan", "tomato")
+		Expect(r.Diets).To(Equal([]recipe.Diet{recipe.DietVegan, recipe.DietVegetarian, recipe.DietPescatarian}))
+		Expect(r.Vegetarian).To(BeTrue())
+
+		// The milk contradicts vegan and dairy-free, vegan implies the other labels
+		r = given(nil, []recipe.Diet{recipe.DietVegan, recipe.DietDairyFree}, "seitan", "milk")
+		Expect(r.Diets).To(Equal([]recipe.Diet{recipe.DietVegetarian, recipe.DietPescatarian}))
+		Expect(r.Allergens).To(Equal([]recipe.Allergen{recipe.AllergenMilk}))
+
+		r = given(nil, []recipe.Diet{recipe.DietVegetarian}, "seitan", "chicken breast")
+		Expect(r.Diets).To(BeEmpty())
+		Expect(r.Vegetarian).To(BeFalse())
+
+		// The allergens of the animal products contradict the labels too
+		r = given(&recipe.AllergenOverrides{Add: []recipe.Allergen{recipe.AllergenEggs}}, vegan, "seitan")
+		Expect(r.Diets).To(Equal([]recipe.Diet{recipe.DietVegetarian, recipe.DietPescatarian}))
+		r = given(&recipe.AllergenOverrides{Add: []recipe.Allergen{recipe.AllergenFish}}, vegan, "seitan")
+		Expect(r.Diets).To(Equal([]recipe.Diet{recipe.DietPescatarian}))
+
+		r = classify(nil)
+		Expect(r.Diets).To(BeEmpty())
+	})
+
+	It("should store the classification and derive it again on update", func() {
+		ctx := context.Background()
+		storage := catalog.NewGateway(gateways.NewMemoryGateway(), c)
+		r := &recipe.Recipe{Name: "Tomato Salad", Ingredients: []recipe.Ingredient{{Name: "tomato"}}}
+		Expect(storage.Store(ctx, r)).To(Succeed())
+
+		stored, err := storage.GetByID(ctx, r.IDHex())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.HasDiet(recipe.DietVegan)).To(BeTrue())
+
+		stored.Ingredients = append(stored.Ingredients, recipe.Ingredient{Name: "chicken"})
+		Expect(storage.Update(ctx, stored)).To(Succeed())
+		stored, err = storage.GetByID(ctx, r.IDHex())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.HasDiet(recipe.DietVegetarian)).To(BeFalse())
+		Expect(stored.Vegetarian).To(BeFalse())
+	})
+})
@@ -0,0 +1,39 @@
+package catalog
+
+import (
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// Classify sets the allergens and diet labels of the recipe derived from
+// its ingredients, see recipe.Classify. The labels are derived only when
+// every ingredient is in the catalog, an unknown one may be anything, so
+// the given labels are checked against the known ones then
+func (c *Catalog) Classify(r *recipe.Recipe) {
+	var allergens []recipe.Allergen
+	diets := map[recipe.Diet]int{}
+	known := 0
+	for _, ingredient := range r.Ingredients {
+		e, ok := c.Lookup(ingredient.Name)
+		if !ok {
+			continue
+		}
+		known++
+		allergens = append(allergens, e.Allergens...)
+		for _, d := range kindDiets[e.Kind] {
+			diets[d]++
+		}
+	}
+
+	derived := len(r.Ingredients) > 0 && known == len(r.Ingredients)
+	// The labels allowed by all the known ingredients, nil if none is known
+	var kept []recipe.Diet
+	if known > 0 {
+		kept = []recipe.Diet{}
+	}
+	for d, n := range diets {
+		if n == known {
+			kept = append(kept, d)
+		}
+	}
+	r.Classify(allergens, kept, derived)
+}
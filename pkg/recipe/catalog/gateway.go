@@ -0,0 +1,30 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+type catalogGateway struct {
+	recipe.StorageGateway
+	catalog *Catalog
+}
+
+// NewGateway create a storage gateway which derives the allergens and diet
+// labels of the recipes from the catalog and stores them with the recipes,
+// so they are derived again whenever the recipe changes. The recipes
+// stored before get them on their next update
+func NewGateway(s recipe.StorageGateway, catalog *Catalog) recipe.StorageGateway {
+	return &catalogGateway{StorageGateway: s, catalog: catalog}
+}
+
+func (s *catalogGateway) Store(ctx context.Context, r *recipe.Recipe) error {
+	s.catalog.Classify(r)
+	return s.StorageGateway.Store(ctx, r)
+}
+
+func (s *catalogGateway) Update(ctx context.Context, r *recipe.Recipe) error {
+	s.catalog.Classify(r)
+	return s.StorageGateway.Update(ctx, r)
+}
@@ -0,0 +1,242 @@
+package recipe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Allergen is one of the 14 allergens the EU requires to be declared
+type Allergen string
+
+// Allergens of the EU regulation 1169/2011
+const (
+	AllergenGluten      Allergen = "gluten"
+	AllergenCrustaceans Allergen = "crustaceans"
+	AllergenEggs        Allergen = "eggs"
+	AllergenFish        Allergen = "fish"
+	AllergenPeanuts     Allergen = "peanuts"
+	AllergenSoybeans    Allergen = "soybeans"
+	AllergenMilk        Allergen = "milk"
+	AllergenNuts        Allergen = "nuts"
+	AllergenCelery      Allergen = "celery"
+	AllergenMustard     Allergen = "mustard"
+	AllergenSesame      Allergen = "sesame"
+	AllergenSulphites   Allergen = "sulphites"
+	AllergenLupin       Allergen = "lupin"
+	AllergenMolluscs    Allergen = "molluscs"
+)
+
+// Allergens are all the allergens in the order of the regulation, the
+// recipe allergens are listed in it
+var Allergens = []Allergen{
+	AllergenGluten, AllergenCrustaceans, AllergenEggs, AllergenFish, AllergenPeanuts,
+	AllergenSoybeans, AllergenMilk, AllergenNuts, AllergenCelery, AllergenMustard,
+	AllergenSesame, AllergenSulphites, AllergenLupin, AllergenMolluscs,
+}
+
+// ParseAllergen parses the allergen name
+func ParseAllergen(s string) (Allergen, error) {
+	a := Allergen(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Allergens {
+		if a == known {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown allergen %q", ErrValidation, s)
+}
+
+// Diet is the diet label of the recipe
+type Diet string
+
+// Diet labels
+const (
+	DietVegan       Diet = "vegan"
+	DietVegetarian  Diet = "vegetarian"
+	DietPescatarian Diet = "pescatarian"
+	DietGlutenFree  Diet = "gluten-free"
+	DietDairyFree   Diet = "dairy-free"
+	DietNutFree     Diet = "nut-free"
+)
+
+// Diets are all the diet labels, the recipe labels are listed in this order
+var Diets = []Diet{DietVegan, DietVegetarian, DietPescatarian, DietGlutenFree, DietDairyFree, DietNutFree}
+
+// ParseDiet parses the diet label
+func ParseDiet(s string) (Diet, error) {
+	d := Diet(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Diets {
+		if d == known {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown diet %q", ErrValidation, s)
+}
+
+// dietAllergens are the allergens which the allergen-free labels exclude
+var dietAllergens = map[Diet][]Allergen{
+	DietGlutenFree: {AllergenGluten},
+	DietDairyFree:  {AllergenMilk},
+	DietNutFree:    {AllergenNuts, AllergenPeanuts},
+}
+
+// dietImplies are the labels implied by the label, e.g. a vegan recipe is
+// vegetarian as well
+var dietImplies = map[Diet][]Diet{
+	DietVegan:      {DietVegetarian, DietPescatarian},
+	DietVegetarian: {DietPescatarian},
+}
+
+// ingredientDiets are the labels which exclude the meat, fish and animal
+// products, so they depend on the kinds of the ingredients
+var ingredientDiets = []Diet{DietVegan, DietVegetarian, DietPescatarian}
+
+// allergenDiets are the labels which the allergens of the animal products
+// contradict, e.g. a recipe with eggs is not vegan
+var allergenDiets = map[Allergen][]Diet{
+	AllergenEggs:        {DietVegan},
+	AllergenMilk:        {DietVegan},
+	AllergenFish:        {DietVegan, DietVegetarian},
+	AllergenCrustaceans: {DietVegan, DietVegetarian},
+	AllergenMolluscs:    {DietVegan, DietVegetarian},
+}
+
+// AllergenOverrides correct the allergens derived from the ingredients,
+// e.g. the gluten is removed when the recipe is made with gluten-free pasta
+type AllergenOverrides struct {
+	Add    []Allergen `json:"add,omitempty" bson:"add,omitempty"`
+	Remove []Allergen `json:"remove,omitempty" bson:"remove,omitempty"`
+}
+
+// Validate checks the overrides
+func (o *AllergenOverrides) Validate() error {
+	if o == nil {
+		return nil
+	}
+	removed := map[Allergen]bool{}
+	for _, a := range o.Remove {
+		if _, err := ParseAllergen(string(a)); err != nil {
+			return err
+		}
+		removed[a] = true
+	}
+	for _, a := range o.Add {
+		if _, err := ParseAllergen(string(a)); err != nil {
+			return err
+		}
+		if removed[a] {
+			return fmt.Errorf("%w: allergen %q is both added and removed", ErrValidation, a)
+		}
+	}
+	return nil
+}
+
+// Apply returns the allergens with the overrides applied, in the order of
+// the regulation
+func (o *AllergenOverrides) Apply(allergens []Allergen) []Allergen {
+	set := map[Allergen]bool{}
+	for _, a := range allergens {
+		set[a] = true
+	}
+	if o != nil {
+		for _, a := range o.Add {
+			set[a] = true
+		}
+		for _, a := range o.Remove {
+			delete(set, a)
+		}
+	}
+	var applied []Allergen
+	for _, a := range Allergens {
+		if set[a] {
+			applied = append(applied, a)
+		}
+	}
+	return applied
+}
+
+// HasDiet tells whether the recipe has the diet label
+func (r *Recipe) HasDiet(d Diet) bool {
+	for _, label := range r.Diets {
+		if label == d {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAllergen tells whether the recipe contains the allergen
+func (r *Recipe) HasAllergen(a Allergen) bool {
+	for _, allergen := range r.Allergens {
+		if allergen == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify sets the allergens of the recipe to the derived ones with the
+// overrides applied, and its diet labels. The diets are the labels which
+// every known ingredient allows, nil if no ingredient is known. When derived
+// is set all the ingredients are known, and the labels are the diets with
+// the allergen-free labels added by the allergens. Otherwise the labels are
+// the given ones, and only those allowed by the known ingredients are kept.
+// In both cases the implied labels are added, the labels contradicting the
+// allergens are dropped and the vegetarian flag follows them
+func (r *Recipe) Classify(allergens []Allergen, diets []Diet, derived bool) {
+	r.Allergens = r.AllergenOverrides.Apply(allergens)
+
+	set := map[Diet]bool{}
+	if derived {
+		for _, d := range diets {
+			set[d] = true
+		}
+		for d := range dietAllergens {
+			set[d] = true
+		}
+	} else {
+		for _, d := range r.Diets {
+			set[d] = true
+		}
+		if r.Vegetarian {
+			set[DietVegetarian] = true
+		}
+	}
+	for _, d := range []Diet{DietVegan, DietVegetarian} {
+		if set[d] {
+			for _, implied := range dietImplies[d] {
+				set[implied] = true
+			}
+		}
+	}
+	if !derived && diets != nil {
+		allowed := map[Diet]bool{}
+		for _, d := range diets {
+			allowed[d] = true
+		}
+		for _, d := range ingredientDiets {
+			if !allowed[d] {
+				delete(set, d)
+			}
+		}
+	}
+	for d, excluded := range dietAllergens {
+		for _, a := range excluded {
+			if r.HasAllergen(a) {
+				delete(set, d)
+			}
+		}
+	}
+	for _, a := range r.Allergens {
+		for _, d := range allergenDiets[a] {
+			delete(set, d)
+		}
+	}
+
+	r.Diets = nil
+	for _, d := range Diets {
+		if set[d] {
+			r.Diets = append(r.Diets, d)
+		}
+	}
+	r.Vegetarian = set[DietVegetarian]
+}
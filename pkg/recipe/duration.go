@@ -0,0 +1,113 @@
+package recipe
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseISODuration parses the ISO-8601 duration such as "PT20M" or "PT1H30M".
+// Years and months are not supported, since they have no fixed length
+func ParseISODuration(s string) (time.Duration, error) {
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil || s == "P" || s[len(s)-1] == 'T' {
+		return 0, fmt.Errorf("invalid ISO-8601 duration: %q", s)
+	}
+
+	var d time.Duration
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute}
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO-8601 duration: %q", s)
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[5] != "" {
+		sec, err := strconv.ParseFloat(m[5], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO-8601 duration: %q", s)
+		}
+		d += time.Duration(sec * float64(time.Second))
+	}
+	return d, nil
+}
+
+// Duration is the time span which JSON form is the ISO-8601 duration such
+// as "PT20M". The empty string and null are the zero duration
+type Duration time.Duration
+
+// ParseDuration parses the ISO-8601 duration, the empty string is zero
+func ParseDuration(s string) (Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := ParseISODuration(s)
+	return Duration(d), err
+}
+
+// Seconds returns the duration in whole seconds
+func (d Duration) Seconds() int64 {
+	return int64(time.Duration(d) / time.Second)
+}
+
+// String returns the ISO-8601 form of the duration in hours, minutes and
+// seconds, e.g. "PT1H30M". The zero duration is "PT0S"
+func (d Duration) String() string {
+	if d == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString("PT")
+	rest := time.Duration(d)
+	if h := rest / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		rest -= h * time.Hour
+	}
+	if m := rest / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		rest -= m * time.Minute
+	}
+	if rest > 0 {
+		b.WriteString(strconv.FormatFloat(rest.Seconds(), 'f', -1, 64))
+		b.WriteString("S")
+	}
+	return b.String()
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = 0
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be an ISO-8601 string such as \"PT20M\"")
+	}
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// ComputeTotalTime sets the total time of the recipe to the sum of the
+// preparation and cooking times. The storages call it on every read
+func (r *Recipe) ComputeTotalTime() {
+	r.TotalTime = r.PrepTime + r.CookTime
+}
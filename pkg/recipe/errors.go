@@ -0,0 +1,16 @@
+package recipe
+
+import "errors"
+
+// Errors returned by the storage gateways and the usecases. They are
+// usually wrapped with the details, so check them with errors.Is
+var (
+	// ErrNotFound is returned when the recipe does not exist
+	ErrNotFound = errors.New("recipe not found")
+	// ErrInvalidID is returned when the recipe ID is malformed
+	ErrInvalidID = errors.New("invalid recipe ID")
+	// ErrValidation is returned when the recipe or the request is not valid
+	ErrValidation = errors.New("validation failed")
+	// ErrConflict is returned when the change conflicts with the stored data
+	ErrConflict = errors.New("recipe conflict")
+)
@@ -0,0 +1,157 @@
+package recipe
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Filter is the structured filter of the recipes. The nil and empty fields
+// do not filter, the ranges include their bounds. The recipes have all the
+// diet labels, tags and none of the allergens listed, and any of the
+// cuisines and meals
+type Filter struct {
+	Difficulties    []Difficulty
+	Vegetarian      *bool
+	Diets           []Diet
+	AllergenFree    []Allergen
+	Cuisines        []string
+	Meals           []string
+	Tags            []string
+	MinRating       *float64
+	MaxRating       *float64
+	MinRatingsCount *int64
+	MaxRatingsCount *int64
+	MinPrepTime     *time.Duration
+	MaxPrepTime     *time.Duration
+	MinCookTime     *time.Duration
+	MaxCookTime     *time.Duration
+	MinTotalTime    *time.Duration
+	MaxTotalTime    *time.Duration
+}
+
+// Validate checks the ranges of the filter
+func (f *Filter) Validate() error {
+	for _, d := range f.Difficulties {
+		if d < Easy || d > Hard {
+			return fmt.Errorf("%w: unknown difficulty %d", ErrValidation, d)
+		}
+	}
+	for _, d := range f.Diets {
+		if _, err := ParseDiet(string(d)); err != nil {
+			return err
+		}
+	}
+	for _, a := range f.AllergenFree {
+		if _, err := ParseAllergen(string(a)); err != nil {
+			return err
+		}
+	}
+	if f.MinRating != nil && f.MaxRating != nil && *f.MinRating > *f.MaxRating {
+		return fmt.Errorf("%w: minimal rating is above the maximal one", ErrValidation)
+	}
+	if f.MinRatingsCount != nil && f.MaxRatingsCount != nil && *f.MinRatingsCount > *f.MaxRatingsCount {
+		return fmt.Errorf("%w: minimal ratings count is above the maximal one", ErrValidation)
+	}
+	if f.MinPrepTime != nil && f.MaxPrepTime != nil && *f.MinPrepTime > *f.MaxPrepTime {
+		return fmt.Errorf("%w: minimal preparation time is above the maximal one", ErrValidation)
+	}
+	if f.MinCookTime != nil && f.MaxCookTime != nil && *f.MinCookTime > *f.MaxCookTime {
+		return fmt.Errorf("%w: minimal cooking time is above the maximal one", ErrValidation)
+	}
+	if f.MinTotalTime != nil && f.MaxTotalTime != nil && *f.MinTotalTime > *f.MaxTotalTime {
+		return fmt.Errorf("%w: minimal total time is above the maximal one", ErrValidation)
+	}
+	return nil
+}
+
+// Match tells whether the recipe passes the filter. The storages which
+// cannot translate the filter to their queries use it
+func (f *Filter) Match(r *Recipe) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Difficulties) > 0 {
+		found := false
+		for _, d := range f.Difficulties {
+			found = found || d == r.Difficulty
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Vegetarian != nil && *f.Vegetarian != r.Vegetarian {
+		return false
+	}
+	for _, d := range f.Diets {
+		if !r.HasDiet(d) {
+			return false
+		}
+	}
+	for _, a := range f.AllergenFree {
+		if r.HasAllergen(a) {
+			return false
+		}
+	}
+	if !hasAnyTerm(r, KindCuisine, f.Cuisines) || !hasAnyTerm(r, KindMeal, f.Meals) {
+		return false
+	}
+	for _, slug := range f.Tags {
+		if !r.HasTerm(KindTag, slug) {
+			return false
+		}
+	}
+	if (f.MinRating != nil && r.AverageRating < *f.MinRating) ||
+		(f.MaxRating != nil && r.AverageRating > *f.MaxRating) {
+		return false
+	}
+	if (f.MinRatingsCount != nil && r.RatingsCount < *f.MinRatingsCount) ||
+		(f.MaxRatingsCount != nil && r.RatingsCount > *f.MaxRatingsCount) {
+		return false
+	}
+	return durationBetween(r.PrepTime, f.MinPrepTime, f.MaxPrepTime) &&
+		durationBetween(r.CookTime, f.MinCookTime, f.MaxCookTime) &&
+		durationBetween(r.PrepTime+r.CookTime, f.MinTotalTime, f.MaxTotalTime)
+}
+
+// hasAnyTerm tells whether the recipe has any of the terms, if they are given
+func hasAnyTerm(r *Recipe, kind TermKind, slugs []string) bool {
+	for _, slug := range slugs {
+		if r.HasTerm(kind, slug) {
+			return true
+		}
+	}
+	return len(slugs) == 0
+}
+
+// durationBetween tells whether the duration in whole seconds is in the range
+func durationBetween(d Duration, min, max *time.Duration) bool {
+	seconds := time.Duration(d.Seconds()) * time.Second
+	return (min == nil || seconds >= *min) && (max == nil || seconds <= *max)
+}
+
+// Facets are the numbers of the filtered recipes per difficulty and per
+// vegetarian flag. The keys are the difficulty levels and "true"/"false"
+type Facets struct {
+	Difficulty map[string]int64 `json:"difficulty"`
+	Vegetarian map[string]int64 `json:"vegetarian"`
+}
+
+// NewFacets returns the empty facets
+func NewFacets() *Facets {
+	return &Facets{
+		Difficulty: map[string]int64{},
+		Vegetarian: map[string]int64{},
+	}
+}
+
+// Add counts the recipe in the facets
+func (f *Facets) Add(r *Recipe) {
+	f.AddCount(r.Difficulty, r.Vegetarian, 1)
+}
+
+// AddCount counts n recipes of the difficulty and vegetarian flag in the facets
+func (f *Facets) AddCount(d Difficulty, vegetarian bool, n int64) {
+	f.Difficulty[strconv.Itoa(int(d))] += n
+	f.Vegetarian[strconv.FormatBool(vegetarian)] += n
+}
@@ -0,0 +1,188 @@
+package recipe
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// FuzzyAuto chooses the number of the allowed edits by the length of the
+// term: none up to 2 letters, one up to 5 letters and two for longer terms
+const FuzzyAuto = -1
+
+// Limits of the typo tolerance
+const (
+	MaxFuzziness   = 2
+	MaxSuggestions = 5
+)
+
+// ParseFuzziness parses the typo tolerance of the search: the number of
+// the allowed edits, "auto" or a boolean
+func ParseFuzziness(s string) (int, error) {
+	switch s {
+	case "", "false":
+		return 0, nil
+	case "auto", "true":
+		return FuzzyAuto, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > MaxFuzziness {
+		return 0, fmt.Errorf("%w: fuzzy must be auto or from 0 to %d", ErrValidation, MaxFuzziness)
+	}
+	return n, nil
+}
+
+// maxEdits returns the number of the edits the term allows with the fuzziness
+func maxEdits(term string, fuzziness int) int {
+	if fuzziness != FuzzyAuto {
+		return fuzziness
+	}
+	switch n := utf8.RuneCountInString(term); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	}
+	return 2
+}
+
+// EditDistance returns the number of the insertions, deletions,
+// substitutions and transpositions of the adjacent letters turning a to b
+func EditDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// Three rows of the distances are enough for the transpositions
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d := min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] && prev2[j-2]+1 < d {
+				d = prev2[j-2] + 1
+			}
+			cur[j] = d
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// FuzzyMatch returns the similarity of the term to the closest word, from
+// 0 to 1, if it is within the allowed edits
+func FuzzyMatch(term string, words []string, fuzziness int) (float64, bool) {
+	limit := maxEdits(term, fuzziness)
+	best := -1
+	for _, word := range words {
+		if d := EditDistance(term, word); d <= limit && (best < 0 || d < best) {
+			best = d
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return 1 - float64(best)/float64(utf8.RuneCountInString(term)+1), true
+}
+
+// CloseWords returns the words within the allowed edits of any term of the
+// text, the words equal to the terms included
+func CloseWords(text string, words []string, fuzziness int) []string {
+	terms := SearchTerms(text)
+	near := []string{}
+	for _, word := range words {
+		for _, term := range terms {
+			if EditDistance(term, word) <= maxEdits(term, fuzziness) {
+				near = append(near, word)
+				break
+			}
+		}
+	}
+	return near
+}
+
+// Vocabulary is the number of the recipe names per word
+type Vocabulary map[string]int
+
+// Add counts the words of the name
+func (v Vocabulary) Add(name string) {
+	seen := map[string]bool{}
+	for _, word := range SearchTerms(name) {
+		if !seen[word] {
+			seen[word] = true
+			v[word]++
+		}
+	}
+}
+
+// Remove uncounts the words of the name
+func (v Vocabulary) Remove(name string) {
+	seen := map[string]bool{}
+	for _, word := range SearchTerms(name) {
+		if !seen[word] {
+			seen[word] = true
+			if v[word]--; v[word] <= 0 {
+				delete(v, word)
+			}
+		}
+	}
+}
+
+// Suggest returns the known words closest to the unknown terms of the
+// text, by the distance and then by the number of the names using them
+func (v Vocabulary) Suggest(text string) []string {
+	type suggestion struct {
+		word     string
+		distance int
+	}
+	var found []suggestion
+	seen := map[string]bool{}
+	for _, term := range SearchTerms(text) {
+		if v[term] > 0 {
+			continue
+		}
+		limit := maxEdits(term, FuzzyAuto)
+		for word := range v {
+			if d := EditDistance(term, word); d <= limit && !seen[word] {
+				seen[word] = true
+				found = append(found, suggestion{word, d})
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if v[a.word] != v[b.word] {
+			return v[a.word] > v[b.word]
+		}
+		return a.word < b.word
+	})
+	if len(found) > MaxSuggestions {
+		found = found[:MaxSuggestions]
+	}
+	words := make([]string, 0, len(found))
+	for _, s := range found {
+		words = append(words, s.word)
+	}
+	return words
+}
@@ -0,0 +1,13 @@
+package gateways
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGateways(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Gateways Suite")
+}
@@ -0,0 +1,550 @@
+package gateways
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type memGateway struct {
+	mu      sync.RWMutex
+	ids     []string
+	recipes map[string]*recipe.Recipe
+	terms   map[recipe.TermKind]map[string]*recipe.Term
+}
+
+// NewMemoryGateway create a thread-safe in-memory storage gateway
+func NewMemoryGateway() recipe.StorageGateway {
+	return &memGateway{
+		recipes: make(map[string]*recipe.Recipe),
+		terms:   make(map[recipe.TermKind]map[string]*recipe.Term),
+	}
+}
+
+// memID converts the recipe ID to the key of the storage
+func memID(id interface{}) (string, error) {
+	switch v := id.(type) {
+	case string:
+		if bson.IsObjectIdHex(v) {
+			return v, nil
+		}
+	case bson.ObjectId:
+		if v.Valid() {
+			return v.Hex(), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %v", recipe.ErrInvalidID, id)
+}
+
+// copyRecipe returns a copy of the recipe, so the stored entries
+// cannot be modified outside of the gateway
+func copyRecipe(r *recipe.Recipe) *recipe.Recipe {
+	c := *r
+	if r.Ingredients != nil {
+		c.Ingredients = append([]recipe.Ingredient(nil), r.Ingredients...)
+	}
+	if r.Steps != nil {
+		c.Steps = make([]recipe.Step, len(r.Steps))
+		for i, step := range r.Steps {
+			step.Ingredients = append([]string(nil), step.Ingredients...)
+			c.Steps[i] = step
+		}
+	}
+	c.Nutrition = r.Nutrition.Copy()
+	if r.Diets != nil {
+		c.Diets = append([]recipe.Diet(nil), r.Diets...)
+	}
+	if r.Allergens != nil {
+		c.Allergens = append([]recipe.Allergen(nil), r.Allergens...)
+	}
+	for _, slugs := range []*[]string{&c.Cuisines, &c.Meals, &c.Tags} {
+		if *slugs != nil {
+			*slugs = append([]string(nil), *slugs...)
+		}
+	}
+	if r.AllergenOverrides != nil {
+		c.AllergenOverrides = &recipe.AllergenOverrides{
+			Add:    append([]recipe.Allergen(nil), r.AllergenOverrides.Add...),
+			Remove: append([]recipe.Allergen(nil), r.AllergenOverrides.Remove...),
+		}
+	}
+	c.Images = recipe.CopyImages(r.Images)
+	c.ComputeTotalTime()
+	return &c
+}
+
+func (s *memGateway) GetRange(ctx context.Context, start, limit uint64) ([]*recipe.Recipe, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var recipes []*recipe.Recipe
+	for i := start; i < uint64(len(s.ids)); i++ {
+		if limit > 0 && uint64(len(recipes)) >= limit {
+			break
+		}
+		recipes = append(recipes, copyRecipe(s.recipes[s.ids[i]]))
+	}
+	return recipes, nil
+}
+
+func (s *memGateway) GetPage(ctx context.Context, q *recipe.RangeQuery) ([]*recipe.Recipe, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Walk the recipes in the order of the range
+	desc := rangeDesc(q)
+	before := func(a, b *recipe.Recipe) bool {
+		c := compareValues(a.SortValue(q.Sort.Field), b.SortValue(q.Sort.Field))
+		if c == 0 {
+			c = strings.Compare(a.IDHex(), b.IDHex())
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	all := make([]*recipe.Recipe, 0, len(s.ids))
+	for _, key := range s.ids {
+		if r := s.recipes[key]; q.Filter.Match(r) {
+			all = append(all, r)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return before(all[i], all[j]) })
+
+	var recipes []*recipe.Recipe
+	for _, r := range all {
+		if q.Limit > 0 && len(recipes) >= q.Limit {
+			break
+		}
+		if q.Cursor != nil {
+			c := compareValues(r.SortValue(q.Sort.Field), q.Cursor.Value)
+			if c == 0 {
+				c = strings.Compare(r.IDHex(), q.Cursor.ID)
+			}
+			if (desc && c >= 0) || (!desc && c <= 0) {
+				continue
+			}
+		}
+		recipes = append(recipes, copyRecipe(r))
+	}
+	if q.Cursor != nil && q.Cursor.Backward {
+		reverseRecipes(recipes)
+	}
+	return recipes, nil
+}
+
+func (s *memGateway) Count(ctx context.Context, filter *recipe.Filter) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int64
+	for _, r := range s.recipes {
+		if filter.Match(r) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memGateway) Facets(ctx context.Context, filter *recipe.Filter) (*recipe.Facets, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	facets := recipe.NewFacets()
+	for _, r := range s.recipes {
+		if filter.Match(r) {
+			facets.Add(r)
+		}
+	}
+	return facets, nil
+}
+
+func (s *memGateway) GetByID(ctx context.Context, id string) (*recipe.Recipe, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key, err := memID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.recipes[key]
+	if !ok {
+		return nil, recipe.ErrNotFound
+	}
+	return copyRecipe(r), nil
+}
+
+func (s *memGateway) DeleteByID(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := memID(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.recipes[key]; !ok {
+		return recipe.ErrNotFound
+	}
+	delete(s.recipes, key)
+	for i, v := range s.ids {
+		if v == key {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memGateway) Store(ctx context.Context, r *recipe.Recipe) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Generate the ID the same way as MongoDB does
+	if r.ID == nil {
+		r.ID = bson.NewObjectId().Hex()
+	}
+	key, err := memID(r.ID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.recipes[key]; ok {
+		return fmt.Errorf("%w: duplicate ID %s", recipe.ErrConflict, key)
+	}
+	r.Version = 1
+	stored := copyRecipe(r)
+	stored.ID = key
+	s.recipes[key] = stored
+	s.ids = append(s.ids, key)
+	return nil
+}
+
+func (s *memGateway) Update(ctx context.Context, r *recipe.Recipe) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := memID(r.ID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.recipes[key]
+	if !ok {
+		return recipe.ErrNotFound
+	}
+	if r.Version != 0 && r.Version != stored.Version {
+		return fmt.Errorf("%w: version %d is stale", recipe.ErrConflict, r.Version)
+	}
+	stored.Name = r.Name
+	stored.PrepTime = r.PrepTime
+	stored.CookTime = r.CookTime
+	stored.Difficulty = r.Difficulty
+	stored.Vegetarian = r.Vegetarian
+	stored.Servings = r.Servings
+	c := copyRecipe(r)
+	stored.Ingredients = c.Ingredients
+	stored.Steps = c.Steps
+	stored.TotalTime = c.TotalTime
+	stored.Nutrition = c.Nutrition
+	stored.Diets = c.Diets
+	stored.Allergens = c.Allergens
+	stored.AllergenOverrides = c.AllergenOverrides
+	stored.Cuisines = c.Cuisines
+	stored.Meals = c.Meals
+	stored.Tags = c.Tags
+	stored.Version++
+	r.Version = stored.Version
+	r.AverageRating = stored.AverageRating
+	r.RatingsCount = stored.RatingsCount
+	r.RatingsSum = stored.RatingsSum
+	r.Images = recipe.CopyImages(stored.Images)
+	return nil
+}
+
+func (s *memGateway) Delete(ctx context.Context, r *recipe.Recipe) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := memID(r.ID)
+	if err != nil {
+		return err
+	}
+	return s.DeleteByID(ctx, key)
+}
+
+func (s *memGateway) Search(ctx context.Context, q *recipe.SearchQuery) (*recipe.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recipes := make([]*recipe.Recipe, 0, len(s.ids))
+	for _, key := range s.ids {
+		recipes = append(recipes, s.recipes[key])
+	}
+	res, err := scanSearch(recipes, q)
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range res.Hits {
+		hit.Recipe = copyRecipe(hit.Recipe)
+	}
+	return res, nil
+}
+
+func (s *memGateway) Suggest(ctx context.Context, q *recipe.SuggestQuery) ([]*recipe.Suggestion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []*recipe.Recipe
+	for _, key := range s.ids {
+		if r := s.recipes[key]; q.Match(r.Name) {
+			found = append(found, r)
+		}
+	}
+	recipe.SortByPopularity(found)
+	if len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+	return recipe.Suggestions(found), nil
+}
+
+func (s *memGateway) ApplyRating(ctx context.Context, id string, score uint8) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := memID(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.recipes[key]
+	if !ok {
+		return recipe.ErrNotFound
+	}
+	stored.Version++
+	stored.AddRating(score)
+	return nil
+}
+
+func (s *memGateway) AddImage(ctx context.Context, id string, img *recipe.Image) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key, err := memID(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.recipes[key]
+	if !ok {
+		return recipe.ErrNotFound
+	}
+	if len(stored.Images) >= recipe.MaxImages {
+		return recipe.ErrTooManyImages
+	}
+	stored.Images = append(stored.Images, img.Copy())
+	stored.Version++
+	return nil
+}
+
+func (s *memGateway) RemoveImage(ctx context.Context, id, imageID string) (*recipe.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	key, err := memID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.recipes[key]
+	if !ok {
+		return nil, recipe.ErrNotFound
+	}
+	for i, img := range stored.Images {
+		if img.ID == imageID {
+			stored.Images = append(stored.Images[:i:i], stored.Images[i+1:]...)
+			stored.Version++
+			return &img, nil
+		}
+	}
+	return nil, recipe.ErrImageNotFound
+}
+
+// termCount returns the number of the recipes of the term
+func (s *memGateway) termCount(kind recipe.TermKind, slug string) int64 {
+	var n int64
+	for _, r := range s.recipes {
+		if r.HasTerm(kind, slug) {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *memGateway) Terms(ctx context.Context, kind recipe.TermKind) ([]*recipe.Term, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := make([]*recipe.Term, 0, len(s.terms[kind]))
+	for slug, t := range s.terms[kind] {
+		c := *t
+		c.Count = s.termCount(kind, slug)
+		terms = append(terms, &c)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Name != terms[j].Name {
+			return terms[i].Name < terms[j].Name
+		}
+		return terms[i].Slug < terms[j].Slug
+	})
+	return terms, nil
+}
+
+func (s *memGateway) GetTerm(ctx context.Context, kind recipe.TermKind, slug string) (*recipe.Term, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.terms[kind][slug]
+	if !ok {
+		return nil, recipe.ErrTermNotFound
+	}
+	c := *t
+	c.Count = s.termCount(kind, slug)
+	return &c, nil
+}
+
+func (s *memGateway) StoreTerm(ctx context.Context, t *recipe.Term) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.terms[t.Kind][t.Slug]; ok {
+		return fmt.Errorf("%w: duplicate %s %s", recipe.ErrConflict, t.Kind, t.Slug)
+	}
+	if s.terms[t.Kind] == nil {
+		s.terms[t.Kind] = make(map[string]*recipe.Term)
+	}
+	c := *t
+	c.Count = 0
+	s.terms[t.Kind][t.Slug] = &c
+	return nil
+}
+
+func (s *memGateway) UpdateTerm(ctx context.Context, slug string, t *recipe.Term) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.terms[t.Kind][slug]; !ok {
+		return recipe.ErrTermNotFound
+	}
+	if _, ok := s.terms[t.Kind][t.Slug]; ok && t.Slug != slug {
+		return fmt.Errorf("%w: duplicate %s %s", recipe.ErrConflict, t.Kind, t.Slug)
+	}
+	delete(s.terms[t.Kind], slug)
+	c := *t
+	c.Count = 0
+	s.terms[t.Kind][t.Slug] = &c
+	if t.Slug != slug {
+		s.replaceTerm(t.Kind, slug, t.Slug)
+	}
+	return nil
+}
+
+func (s *memGateway) DeleteTerm(ctx context.Context, kind recipe.TermKind, slug string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.terms[kind][slug]; !ok {
+		return recipe.ErrTermNotFound
+	}
+	delete(s.terms[kind], slug)
+	s.replaceTerm(kind, slug, "")
+	return nil
+}
+
+// replaceTerm replaces the term in all the recipes, which versions change
+func (s *memGateway) replaceTerm(kind recipe.TermKind, slug, newSlug string) {
+	for _, r := range s.recipes {
+		if r.ReplaceTerm(kind, slug, newSlug) {
+			r.Version++
+		}
+	}
+}
@@ -0,0 +1,617 @@
+package gateways
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// mongoFields returns the recipe fields set by the update. The ratings and
+// the images are changed apart from the update, so they are kept. Plain
+// maps are understood by both mgo and the official driver
+func mongoFields(r *recipe.Recipe) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        r.Name,
+		"prepTime":    r.PrepTime.String(),
+		"cookTime":    r.CookTime.String(),
+		"difficulty":  r.Difficulty,
+		"vegetarian":  r.Vegetarian,
+		"servings":    r.Servings,
+		"ingredients": r.Ingredients,
+		"steps":       r.Steps,
+		"nutrition":   r.Nutrition,
+
+		"diets":             r.Diets,
+		"allergens":         r.Allergens,
+		"allergenOverrides": r.AllergenOverrides,
+		"cuisines":          r.Cuisines,
+		"meals":             r.Meals,
+		"tags":              r.Tags,
+
+		"prepTimeSeconds":  r.PrepTime.Seconds(),
+		"cookTimeSeconds":  r.CookTime.Seconds(),
+		"totalTimeSeconds": (r.PrepTime + r.CookTime).Seconds(),
+		"nameWords":        recipe.SearchTerms(r.Name),
+	}
+}
+
+// mongoUpdate returns the update of the recipe fields which increments the version
+func mongoUpdate(r *recipe.Recipe) map[string]interface{} {
+	return map[string]interface{}{
+		"$set": mongoFields(r),
+		"$inc": map[string]interface{}{"version": 1},
+	}
+}
+
+// mongoVersion is the projection of the recipe version, the ratings and
+// the images, which are changed apart from the other fields
+type mongoVersion struct {
+	Version       int64          `bson:"version"`
+	AverageRating float64        `bson:"averageRating"`
+	RatingsCount  int64          `bson:"ratingsCount"`
+	RatingsSum    float64        `bson:"ratingsSum"`
+	Images        []recipe.Image `bson:"images"`
+}
+
+// mongoKept returns the projection of the fields kept by the update
+func mongoKept() map[string]interface{} {
+	return map[string]interface{}{"version": 1, "averageRating": 1, "ratingsCount": 1, "ratingsSum": 1, "images": 1}
+}
+
+// restore sets the fields kept by the update to the recipe
+func (v *mongoVersion) restore(r *recipe.Recipe) {
+	r.Version = v.Version
+	r.AverageRating = v.AverageRating
+	r.RatingsCount = v.RatingsCount
+	r.RatingsSum = v.RatingsSum
+	r.Images = v.Images
+}
+
+// image returns the image of the projection by ID
+func (v *mongoVersion) image(id string) *recipe.Image {
+	for i := range v.Images {
+		if v.Images[i].ID == id {
+			return &v.Images[i]
+		}
+	}
+	return nil
+}
+
+// mongoAddImage returns the query and the update which add the image to
+// the recipe unless it has MaxImages already
+func mongoAddImage(img *recipe.Image) (map[string]interface{}, map[string]interface{}) {
+	query := map[string]interface{}{
+		fmt.Sprintf("images.%d", recipe.MaxImages-1): map[string]interface{}{"$exists": false},
+	}
+	update := map[string]interface{}{
+		"$push": map[string]interface{}{"images": img},
+		"$inc":  map[string]interface{}{"version": 1},
+	}
+	return query, update
+}
+
+// mongoRemoveImage returns the update which removes the image of the recipe
+func mongoRemoveImage(imageID string) map[string]interface{} {
+	return map[string]interface{}{
+		"$pull": map[string]interface{}{"images": map[string]interface{}{"id": imageID}},
+		"$inc":  map[string]interface{}{"version": 1},
+	}
+}
+
+// mongoDocument is the stored recipe. The durations are kept as ISO-8601
+// strings, so the documents stay readable by the older versions, and their
+// seconds with the other derived fields are used by the queries. The
+// derived fields are never read back into the recipe
+type mongoDocument struct {
+	recipe.Recipe    `bson:",inline"`
+	PrepTime         string   `bson:"prepTime"`
+	CookTime         string   `bson:"cookTime"`
+	PrepTimeSeconds  int64    `bson:"prepTimeSeconds"`
+	CookTimeSeconds  int64    `bson:"cookTimeSeconds"`
+	TotalTimeSeconds int64    `bson:"totalTimeSeconds"`
+	NameWords        []string `bson:"nameWords"`
+	Popularity       float64  `bson:"popularity"`
+}
+
+// newMongoDocument returns the document of the recipe to store
+func newMongoDocument(r *recipe.Recipe) *mongoDocument {
+	return &mongoDocument{
+		Recipe:           *r,
+		PrepTime:         r.PrepTime.String(),
+		CookTime:         r.CookTime.String(),
+		PrepTimeSeconds:  r.PrepTime.Seconds(),
+		CookTimeSeconds:  r.CookTime.Seconds(),
+		TotalTimeSeconds: (r.PrepTime + r.CookTime).Seconds(),
+		NameWords:        recipe.SearchTerms(r.Name),
+		Popularity:       r.Popularity(),
+	}
+}
+
+// toRecipe returns the recipe of the document. The durations which cannot
+// be parsed, e.g. the free-form ones stored before, are zero
+func (d *mongoDocument) toRecipe() *recipe.Recipe {
+	r := d.Recipe
+	r.PrepTime, _ = recipe.ParseDuration(d.PrepTime)
+	r.CookTime, _ = recipe.ParseDuration(d.CookTime)
+	r.ComputeTotalTime()
+	return &r
+}
+
+// mongoRecipes returns the recipes of the documents
+func mongoRecipes(docs []*mongoDocument) []*recipe.Recipe {
+	recipes := make([]*recipe.Recipe, 0, len(docs))
+	for _, doc := range docs {
+		recipes = append(recipes, doc.toRecipe())
+	}
+	return recipes
+}
+
+// mongoRating returns the aggregation pipeline update (MongoDB 4.2+) which
+// adds the score to the ratings in a single atomic operation. The average
+// is derived from the sum of the scores the same way as recipe.Recipe.AddRating,
+// the documents stored before the sum have it computed from the average
+func mongoRating(score uint8) []interface{} {
+	ifNull := func(field string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{"$ifNull": []interface{}{field, value}}
+	}
+	count := map[string]interface{}{"$add": []interface{}{ifNull("$ratingsCount", 0), 1}}
+	sum := map[string]interface{}{"$add": []interface{}{
+		ifNull("$ratingsSum", map[string]interface{}{"$multiply": []interface{}{
+			ifNull("$averageRating", 0), ifNull("$ratingsCount", 0),
+		}}),
+		float64(score),
+	}}
+	return []interface{}{map[string]interface{}{"$set": map[string]interface{}{
+		"version":       map[string]interface{}{"$add": []interface{}{ifNull("$version", 0), 1}},
+		"ratingsCount":  count,
+		"ratingsSum":    sum,
+		"averageRating": map[string]interface{}{"$divide": []interface{}{sum, count}},
+	}}, mongoPopularity()}
+}
+
+// mongoPopularity returns the stage of the pipeline update which recomputes
+// the popularity from the ratings the same way as recipe.Recipe.Popularity
+func mongoPopularity() map[string]interface{} {
+	return map[string]interface{}{"$set": map[string]interface{}{
+		"popularity": map[string]interface{}{"$multiply": []interface{}{
+			"$averageRating",
+			map[string]interface{}{"$ln": map[string]interface{}{"$add": []interface{}{1, "$ratingsCount"}}},
+		}},
+	}}
+}
+
+// mongoBackfillQuery returns the filter of the documents stored without
+// the fields of the suggestions, e.g. before they were introduced
+func mongoBackfillQuery() map[string]interface{} {
+	return map[string]interface{}{"$or": []interface{}{
+		map[string]interface{}{"nameWords": map[string]interface{}{"$exists": false}},
+		map[string]interface{}{"popularity": map[string]interface{}{"$exists": false}},
+	}}
+}
+
+// mongoBackfillFields returns the projection of the name and the ratings,
+// which the fields of the suggestions are computed from
+func mongoBackfillFields() map[string]interface{} {
+	return map[string]interface{}{"name": 1, "averageRating": 1, "ratingsCount": 1}
+}
+
+// mongoBackfill returns the update which sets the fields of the suggestions
+// to the document found by mongoBackfillQuery
+func mongoBackfill(d *mongoDocument) map[string]interface{} {
+	return map[string]interface{}{"$set": map[string]interface{}{
+		"nameWords":  recipe.SearchTerms(d.Name),
+		"popularity": d.Recipe.Popularity(),
+	}}
+}
+
+// mongoSuggest returns the filter of the names with a word starting with
+// every word of the text. The anchored regular expressions on the lower-case
+// words of the names are served by the index of nameWords
+func mongoSuggest(q *recipe.SuggestQuery) map[string]interface{} {
+	var terms []interface{}
+	for _, term := range recipe.SearchTerms(q.Text) {
+		terms = append(terms, map[string]interface{}{
+			"nameWords": map[string]interface{}{"$regex": "^" + regexp.QuoteMeta(term)},
+		})
+	}
+	return map[string]interface{}{"$and": terms}
+}
+
+// mongoFuzzyQuery returns the filter of the names the fuzzy search may
+// match: the names with a word close to a term of the text and, in the text
+// mode, the names containing the text. The words are the distinct words of
+// the names, the filter on nameWords is served by its index
+func mongoFuzzyQuery(q *recipe.SearchQuery, words []string) map[string]interface{} {
+	near := map[string]interface{}{
+		"nameWords": map[string]interface{}{"$in": recipe.CloseWords(q.Text, words, q.Fuzziness)},
+	}
+	if q.Mode == recipe.SearchFullText {
+		return near
+	}
+	text := regexp.QuoteMeta(strings.ToLower(q.Text))
+	return map[string]interface{}{"$or": []interface{}{
+		near,
+		map[string]interface{}{"name": map[string]interface{}{"$regex": text, "$options": "i"}},
+	}}
+}
+
+// mongoVocabularyPipeline returns the aggregation counting the names using
+// each of the words, only the names with the words are read
+func mongoVocabularyPipeline(words []string) []interface{} {
+	return []interface{}{
+		map[string]interface{}{"$match": map[string]interface{}{
+			"nameWords": map[string]interface{}{"$in": words},
+		}},
+		map[string]interface{}{"$project": map[string]interface{}{
+			"words": map[string]interface{}{"$setIntersection": []interface{}{"$nameWords", words}},
+		}},
+		map[string]interface{}{"$unwind": "$words"},
+		map[string]interface{}{"$group": map[string]interface{}{
+			"_id":   "$words",
+			"count": map[string]interface{}{"$sum": 1},
+		}},
+	}
+}
+
+// mongoWordCount is the group of the vocabulary aggregation
+type mongoWordCount struct {
+	Word  string `bson:"_id"`
+	Count int    `bson:"count"`
+}
+
+// mongoVocabulary returns the vocabulary of the groups of the aggregation
+func mongoVocabulary(groups []mongoWordCount) recipe.Vocabulary {
+	vocabulary := recipe.Vocabulary{}
+	for _, g := range groups {
+		vocabulary[g.Word] = g.Count
+	}
+	return vocabulary
+}
+
+// mongoSortKey returns the document key of the sort field
+func mongoSortKey(field recipe.SortField) string {
+	switch field {
+	case recipe.SortByPrepTime, recipe.SortByCookTime, recipe.SortByTotalTime:
+		return string(field) + "Seconds"
+	}
+	return string(field)
+}
+
+// mongoRange returns the keyset filter of the range query, the key to sort
+// by and the direction. The cursor ID must be converted by the gateway
+func mongoRange(q *recipe.RangeQuery, cursorID interface{}) (filter map[string]interface{}, key string, desc bool) {
+	key = mongoSortKey(q.Sort.Field)
+	desc = rangeDesc(q)
+	filter = mongoFilter(q.Filter)
+	if q.Cursor == nil {
+		return filter, key, desc
+	}
+
+	op := "$gt"
+	if desc {
+		op = "$lt"
+	}
+	keyset := map[string]interface{}{"$or": []interface{}{
+		map[string]interface{}{key: map[string]interface{}{op: q.Cursor.Value}},
+		map[string]interface{}{key: q.Cursor.Value, "_id": map[string]interface{}{op: cursorID}},
+	}}
+	if len(filter) == 0 {
+		return keyset, key, desc
+	}
+	return map[string]interface{}{"$and": []interface{}{filter, keyset}}, key, desc
+}
+
+// mongoFilter translates the recipes filter to the query
+func mongoFilter(f *recipe.Filter) map[string]interface{} {
+	query := map[string]interface{}{}
+	if f == nil {
+		return query
+	}
+
+	if len(f.Difficulties) > 0 {
+		query["difficulty"] = map[string]interface{}{"$in": f.Difficulties}
+	}
+	if f.Vegetarian != nil {
+		query["vegetarian"] = *f.Vegetarian
+	}
+	if len(f.Diets) > 0 {
+		query["diets"] = map[string]interface{}{"$all": f.Diets}
+	}
+	if len(f.AllergenFree) > 0 {
+		query["allergens"] = map[string]interface{}{"$nin": f.AllergenFree}
+	}
+	if len(f.Cuisines) > 0 {
+		query["cuisines"] = map[string]interface{}{"$in": f.Cuisines}
+	}
+	if len(f.Meals) > 0 {
+		query["meals"] = map[string]interface{}{"$in": f.Meals}
+	}
+	if len(f.Tags) > 0 {
+		query["tags"] = map[string]interface{}{"$all": f.Tags}
+	}
+
+	between := func(key string, min, max interface{}) {
+		cond := map[string]interface{}{}
+		if min != nil {
+			cond["$gte"] = min
+		}
+		if max != nil {
+			cond["$lte"] = max
+		}
+		if len(cond) > 0 {
+			query[key] = cond
+		}
+	}
+	seconds := func(d *time.Duration) interface{} {
+		if d == nil {
+			return nil
+		}
+		return int64(*d / time.Second)
+	}
+	between("averageRating", floatValue(f.MinRating), floatValue(f.MaxRating))
+	between("ratingsCount", intValue(f.MinRatingsCount), intValue(f.MaxRatingsCount))
+	between("prepTimeSeconds", seconds(f.MinPrepTime), seconds(f.MaxPrepTime))
+	between("cookTimeSeconds", seconds(f.MinCookTime), seconds(f.MaxCookTime))
+	between("totalTimeSeconds", seconds(f.MinTotalTime), seconds(f.MaxTotalTime))
+	return query
+}
+
+// floatValue returns the value or nil, the typed nil pointer would not be nil
+func floatValue(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// intValue returns the value or nil, the typed nil pointer would not be nil
+func intValue(v *int64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// mongoFacetsPipeline returns the aggregation counting the filtered recipes
+// per difficulty and vegetarian flag
+func mongoFacetsPipeline(f *recipe.Filter) []interface{} {
+	return []interface{}{
+		map[string]interface{}{"$match": mongoFilter(f)},
+		map[string]interface{}{"$group": map[string]interface{}{
+			"_id": map[string]interface{}{
+				"difficulty": "$difficulty",
+				"vegetarian": map[string]interface{}{"$ifNull": []interface{}{"$vegetarian", false}},
+			},
+			"count": map[string]interface{}{"$sum": 1},
+		}},
+	}
+}
+
+// mongoFacetGroup is the group of the facets aggregation
+type mongoFacetGroup struct {
+	ID struct {
+		Difficulty recipe.Difficulty `bson:"difficulty"`
+		Vegetarian bool              `bson:"vegetarian"`
+	} `bson:"_id"`
+	Count int64 `bson:"count"`
+}
+
+// mongoFacets collects the groups of the facets aggregation
+func mongoFacets(groups []mongoFacetGroup) *recipe.Facets {
+	facets := recipe.NewFacets()
+	for _, g := range groups {
+		facets.AddCount(g.ID.Difficulty, g.ID.Vegetarian, g.Count)
+	}
+	return facets
+}
+
+// mongoSearch returns the filter matching the recipes found by the search
+// query and the expression of their score. The score of the text and prefix
+// modes is computed the same way as recipe.SearchQuery.Matcher does. $toLower
+// folds ASCII only, so the text may be matched by the case-insensitive regex
+// but not found in the name, such names are scored as if it was found at the
+// start
+func mongoSearch(q *recipe.SearchQuery) (match map[string]interface{}, score interface{}, err error) {
+	text := strings.ToLower(q.Text)
+	name := map[string]interface{}{"$toLower": map[string]interface{}{"$ifNull": []interface{}{"$name", ""}}}
+	atLeastOne := func(v interface{}) map[string]interface{} {
+		return map[string]interface{}{"$max": []interface{}{1, v}}
+	}
+	substring := map[string]interface{}{"$divide": []interface{}{
+		utf8.RuneCountInString(text),
+		map[string]interface{}{"$multiply": []interface{}{
+			atLeastOne(map[string]interface{}{"$strLenCP": name}),
+			atLeastOne(map[string]interface{}{"$add": []interface{}{1, map[string]interface{}{"$indexOfCP": []interface{}{name, text}}}}),
+		}},
+	}}
+	regex := func(pattern, options string) map[string]interface{} {
+		return map[string]interface{}{"name": map[string]interface{}{"$regex": pattern, "$options": options}}
+	}
+
+	switch q.Mode {
+	case recipe.SearchRegex:
+		return regex(q.Text, ""), 1, nil
+	case recipe.SearchPrefix:
+		return regex(`\b`+regexp.QuoteMeta(text), "i"), substring, nil
+	case recipe.SearchFullText:
+		match = map[string]interface{}{"$text": map[string]interface{}{"$search": q.Text}}
+		return match, map[string]interface{}{"$meta": "textScore"}, nil
+	case recipe.SearchQueryLanguage:
+		expr, err := q.Expression()
+		if err != nil {
+			return nil, nil, err
+		}
+		return mongoExpr(expr), 1, nil
+	}
+	return regex(regexp.QuoteMeta(text), "i"), substring, nil
+}
+
+// mongoExpr translates the query expression to the filter
+func mongoExpr(expr recipe.Expr) map[string]interface{} {
+	list := func(exprs []recipe.Expr) []interface{} {
+		filters := make([]interface{}, 0, len(exprs))
+		for _, e := range exprs {
+			filters = append(filters, mongoExpr(e))
+		}
+		return filters
+	}
+
+	switch e := expr.(type) {
+	case recipe.And:
+		return map[string]interface{}{"$and": list(e)}
+	case recipe.Or:
+		return map[string]interface{}{"$or": list(e)}
+	case recipe.Not:
+		return map[string]interface{}{"$nor": []interface{}{mongoExpr(e.Expr)}}
+	case recipe.Text:
+		pattern := regexp.QuoteMeta(strings.ToLower(string(e)))
+		return map[string]interface{}{"name": map[string]interface{}{"$regex": pattern, "$options": "i"}}
+	case recipe.Compare:
+		return mongoCompare(e)
+	}
+	// Unknown expressions match nothing
+	return map[string]interface{}{"_id": map[string]interface{}{"$exists": false}}
+}
+
+// mongoCompare translates the comparison of the field to the filter
+func mongoCompare(e recipe.Compare) map[string]interface{} {
+	var key string
+	value := e.Value
+	switch e.Field {
+	case recipe.FieldDifficulty:
+		key = "difficulty"
+	case recipe.FieldVegetarian:
+		key = "vegetarian"
+	case recipe.FieldRating:
+		key = "averageRating"
+	case recipe.FieldRatingsCount:
+		key = "ratingsCount"
+	case recipe.FieldPrepTime, recipe.FieldCookTime, recipe.FieldTotalTime:
+		key = map[recipe.QueryField]string{
+			recipe.FieldPrepTime:  "prepTimeSeconds",
+			recipe.FieldCookTime:  "cookTimeSeconds",
+			recipe.FieldTotalTime: "totalTimeSeconds",
+		}[e.Field]
+		value = int64(e.Value.(time.Duration) / time.Second)
+	}
+
+	ops := map[recipe.CompareOp]string{
+		recipe.OpLt: "$lt",
+		recipe.OpLe: "$lte",
+		recipe.OpGt: "$gt",
+		recipe.OpGe: "$gte",
+	}
+	if op, ok := ops[e.Op]; ok {
+		return map[string]interface{}{key: map[string]interface{}{op: value}}
+	}
+	return map[string]interface{}{key: value}
+}
+
+// mongoHit is the found recipe with its score
+type mongoHit struct {
+	mongoDocument `bson:",inline"`
+	Score         float64 `bson:"score"`
+}
+
+// mongoHits converts the found documents to the search hits
+func mongoHits(docs []*mongoHit) []*recipe.SearchHit {
+	hits := make([]*recipe.SearchHit, 0, len(docs))
+	for _, doc := range docs {
+		hits = append(hits, &recipe.SearchHit{Recipe: doc.toRecipe(), Score: doc.Score})
+	}
+	return hits
+}
+
+// mongoTermFields are the recipe fields of the term slugs by kind
+var mongoTermFields = map[recipe.TermKind]string{
+	recipe.KindCuisine: "cuisines",
+	recipe.KindMeal:    "meals",
+	recipe.KindTag:     "tags",
+}
+
+// termError converts the not found error of the terms
+func termError(err error) error {
+	if errors.Is(err, recipe.ErrNotFound) {
+		return recipe.ErrTermNotFound
+	}
+	return err
+}
+
+// mongoTermQuery returns the query of the term in the taxonomy collection
+func mongoTermQuery(kind recipe.TermKind, slug string) map[string]interface{} {
+	return map[string]interface{}{"kind": kind, "slug": slug}
+}
+
+// mongoTermCountsPipeline returns the aggregation counting the recipes per
+// term of the kind
+func mongoTermCountsPipeline(kind recipe.TermKind) []interface{} {
+	field := "$" + mongoTermFields[kind]
+	return []interface{}{
+		map[string]interface{}{"$unwind": field},
+		map[string]interface{}{"$group": map[string]interface{}{
+			"_id":   field,
+			"count": map[string]interface{}{"$sum": 1},
+		}},
+	}
+}
+
+// mongoTermCount is the group of the term counts aggregation
+type mongoTermCount struct {
+	Slug  string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
+
+// mongoTermCounts sets the counts of the terms by the groups of the
+// aggregation and sorts the terms by name
+func mongoTermCounts(terms []*recipe.Term, groups []mongoTermCount) []*recipe.Term {
+	counts := map[string]int64{}
+	for _, g := range groups {
+		counts[g.Slug] = g.Count
+	}
+	for _, t := range terms {
+		t.Count = counts[t.Slug]
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Name != terms[j].Name {
+			return terms[i].Name < terms[j].Name
+		}
+		return terms[i].Slug < terms[j].Slug
+	})
+	return terms
+}
+
+// mongoReplaceTerm returns the query of the recipes with the term and the
+// aggregation pipeline update (MongoDB 4.2+) which replaces it by the new
+// slug, or removes it if the new slug is empty or the recipe has it
+// already, and increments the version
+func mongoReplaceTerm(kind recipe.TermKind, slug, newSlug string) (map[string]interface{}, []interface{}) {
+	field := mongoTermFields[kind]
+	removed := map[string]interface{}{"$filter": map[string]interface{}{
+		"input": "$" + field,
+		"cond":  map[string]interface{}{"$ne": []interface{}{"$$this", slug}},
+	}}
+	slugs := interface{}(removed)
+	if newSlug != "" {
+		slugs = map[string]interface{}{"$cond": []interface{}{
+			map[string]interface{}{"$in": []interface{}{newSlug, "$" + field}},
+			removed,
+			map[string]interface{}{"$map": map[string]interface{}{
+				"input": "$" + field,
+				"in": map[string]interface{}{"$cond": []interface{}{
+					map[string]interface{}{"$eq": []interface{}{"$$this", slug}}, newSlug, "$$this",
+				}},
+			}},
+		}}
+	}
+	update := []interface{}{map[string]interface{}{"$set": map[string]interface{}{
+		field:     slugs,
+		"version": map[string]interface{}{"$add": []interface{}{map[string]interface{}{"$ifNull": []interface{}{"$version", 0}}, 1}},
+	}}}
+	return map[string]interface{}{field: slug}, update
+}
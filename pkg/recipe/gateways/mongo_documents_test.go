@@ -0,0 +1,63 @@
+package gateways
+
+import (
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var _ = Describe("mongoBackfill", func() {
+	It("should set the fields of the suggestions to the older documents", func() {
+		// The document stored before the fields of the suggestions
+		legacy := bson.M{
+			"_id":           bson.NewObjectId(),
+			"name":          "Crème Brûlée Tart",
+			"prepTime":      "PT30M",
+			"averageRating": 4.5,
+			"ratingsCount":  10,
+		}
+		projected := bson.M{"_id": legacy["_id"]}
+		for field := range mongoBackfillFields() {
+			projected[field] = legacy[field]
+		}
+		data, err := bson.Marshal(projected)
+		Expect(err).NotTo(HaveOccurred())
+		doc := &mongoDocument{}
+		Expect(bson.Unmarshal(data, doc)).To(Succeed())
+
+		// The same fields as the documents stored now have
+		stored := newMongoDocument(&recipe.Recipe{Name: "Crème Brûlée Tart", AverageRating: 4.5, RatingsCount: 10})
+		Expect(mongoBackfill(doc)).To(Equal(map[string]interface{}{"$set": map[string]interface{}{
+			"nameWords":  stored.NameWords,
+			"popularity": stored.Popularity,
+		}}))
+		Expect(stored.NameWords).NotTo(BeEmpty())
+		Expect(stored.Popularity).To(BeNumerically(">", 0))
+	})
+})
+
+var _ = Describe("mongoFuzzyQuery", func() {
+	words := []string{"chicken", "lasagna", "soup", "kitchen", "tomato"}
+
+	It("should read the names with the words close to the text", func() {
+		q := &recipe.SearchQuery{Text: "Chiken lasagana", Mode: recipe.SearchFullText, Fuzziness: recipe.FuzzyAuto}
+		Expect(mongoFuzzyQuery(q, words)).To(Equal(map[string]interface{}{
+			"nameWords": map[string]interface{}{"$in": []string{"chicken", "lasagna"}},
+		}))
+
+		q = &recipe.SearchQuery{Text: "tom", Mode: recipe.SearchText, Fuzziness: 1}
+		Expect(mongoFuzzyQuery(q, words)).To(Equal(map[string]interface{}{"$or": []interface{}{
+			map[string]interface{}{"nameWords": map[string]interface{}{"$in": []string{}}},
+			map[string]interface{}{"name": map[string]interface{}{"$regex": "tom", "$options": "i"}},
+		}}))
+	})
+
+	It("should count the names of the close words only", func() {
+		pipeline := mongoVocabularyPipeline([]string{"soup"})
+		Expect(pipeline[0]).To(Equal(map[string]interface{}{"$match": map[string]interface{}{
+			"nameWords": map[string]interface{}{"$in": []string{"soup"}},
+		}}))
+		Expect(mongoVocabulary([]mongoWordCount{{Word: "soup", Count: 3}}).Suggest("sop")).To(Equal([]string{"soup"}))
+	})
+})
@@ -0,0 +1,619 @@
+package gateways
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+type mongoGateway struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	taxonomy   *mongo.Collection
+}
+
+// NewMongoDriverGateway create a storage gateway to the MongoDB based on
+// the official MongoDB driver
+func NewMongoDriverGateway(opts MongoOptions) (recipe.StorageGateway, error) {
+	clientOpts, err := mongoClientOptions(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := 10 * time.Second
+	if opts.ConnectTimeout > 0 {
+		timeout = opts.ConnectTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, clientOpts)
+	if err != nil {
+		return nil, err
+	}
+	// Fail fast the same way as mgo.Dial does
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	gw := &mongoGateway{
+		client:     client,
+		collection: client.Database(opts.Database).Collection(opts.Collection),
+		taxonomy:   client.Database(opts.Database).Collection(opts.TaxonomyCollection),
+	}
+
+	// The full-text search needs the text index, the suggestions need the
+	// index of the name words
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "name", Value: "text"}}},
+		{Keys: bson.D{{Key: "nameWords", Value: 1}}},
+	}
+	if _, err := gw.collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	// The slugs of the terms are unique by kind
+	termIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := gw.taxonomy.Indexes().CreateOne(ctx, termIndex); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	// The backfill is not bound by the connect timeout
+	if err := gw.backfill(context.Background()); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	return gw, nil
+}
+
+// backfill sets the fields of the suggestions to the documents stored
+// without them, so the older recipes are suggested too
+func (s *mongoGateway) backfill(ctx context.Context) error {
+	opts := options.Find().SetProjection(mongoBackfillFields())
+	cursor, err := s.collection.Find(ctx, mongoBackfillQuery(), opts)
+	if err != nil {
+		return mongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		doc := &mongoDocument{}
+		if err := cursor.Decode(doc); err != nil {
+			return err
+		}
+		if _, err := s.collection.UpdateByID(ctx, doc.ID, mongoBackfill(doc)); err != nil {
+			return mongoError(err)
+		}
+	}
+	return cursor.Err()
+}
+
+// mongoClientOptions builds the driver client options from the connection options
+func mongoClientOptions(opts *MongoOptions) (*options.ClientOptions, error) {
+	clientOpts := options.Client()
+	if opts.URI != "" {
+		clientOpts.ApplyURI(opts.URI)
+	}
+
+	if addr := opts.address(); addr != "" {
+		clientOpts.SetHosts([]string{addr})
+	}
+	if opts.Username != "" || opts.AuthMechanism != "" || clientOpts.Auth != nil {
+		// The given options override the credential of the URI field by
+		// field, the driver authenticates against the database of the URI
+		// or "admin" unless the source is given
+		cred := options.Credential{}
+		if clientOpts.Auth != nil {
+			cred = *clientOpts.Auth
+		}
+		if opts.Username != "" {
+			cred.Username = opts.Username
+			cred.Password = opts.Password
+			cred.PasswordSet = opts.Password != ""
+		}
+		if opts.AuthSource != "" {
+			cred.AuthSource = opts.AuthSource
+		}
+		if opts.AuthMechanism != "" {
+			cred.AuthMechanism = opts.AuthMechanism
+		}
+		clientOpts.SetAuth(cred)
+	}
+	if opts.TLS {
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		clientOpts.SetTLSConfig(tlsConfig)
+	}
+	if opts.ReplicaSet != "" {
+		clientOpts.SetReplicaSet(opts.ReplicaSet)
+	}
+	if opts.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(opts.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, err
+		}
+		clientOpts.SetReadPreference(rp)
+	}
+	if opts.WriteConcern != "" {
+		majority, w, err := opts.writeConcern()
+		if err != nil {
+			return nil, err
+		}
+		wc := &writeconcern.WriteConcern{W: w}
+		if majority {
+			wc = writeconcern.Majority()
+		}
+		clientOpts.SetWriteConcern(wc)
+	}
+	if opts.MaxPoolSize > 0 {
+		clientOpts.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.ConnectTimeout > 0 {
+		clientOpts.SetConnectTimeout(opts.ConnectTimeout)
+	}
+	if opts.SocketTimeout > 0 {
+		clientOpts.SetSocketTimeout(opts.SocketTimeout)
+	}
+	return clientOpts, clientOpts.Validate()
+}
+
+// mongoObjectID converts the recipe ID to the MongoDB ObjectID
+func mongoObjectID(id interface{}) (primitive.ObjectID, error) {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		if !v.IsZero() {
+			return v, nil
+		}
+	case string:
+		if oid, err := primitive.ObjectIDFromHex(v); err == nil {
+			return oid, nil
+		}
+	case interface{ Hex() string }:
+		// ObjectIds of the mgo driver
+		if oid, err := primitive.ObjectIDFromHex(v.Hex()); err == nil {
+			return oid, nil
+		}
+	}
+	return primitive.NilObjectID, fmt.Errorf("%w: %v", recipe.ErrInvalidID, id)
+}
+
+// mongoError converts the MongoDB driver errors to the recipe errors
+func mongoError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return recipe.ErrNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %v", recipe.ErrConflict, err)
+	}
+	var serr mongo.ServerError
+	if errors.As(err, &serr) && (serr.HasErrorCode(2) || serr.HasErrorCode(51091)) {
+		// BadValue or invalid regular expression
+		return fmt.Errorf("%w: %v", recipe.ErrValidation, err)
+	}
+	return err
+}
+
+// findAll runs the query and decodes all the found recipes
+func (s *mongoGateway) findAll(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]*recipe.Recipe, error) {
+	cursor, err := s.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	var docs []*mongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, mongoError(err)
+	}
+	return mongoRecipes(docs), nil
+}
+
+func (s *mongoGateway) GetRange(ctx context.Context, start, limit uint64) ([]*recipe.Recipe, error) {
+	opts := options.Find().SetSkip(int64(start)).SetLimit(int64(limit))
+	return s.findAll(ctx, bson.M{}, opts)
+}
+
+func (s *mongoGateway) GetPage(ctx context.Context, q *recipe.RangeQuery) ([]*recipe.Recipe, error) {
+	var cursorID interface{}
+	if q.Cursor != nil {
+		oid, err := mongoObjectID(q.Cursor.ID)
+		if err != nil {
+			return nil, err
+		}
+		cursorID = oid
+	}
+
+	filter, key, desc := mongoRange(q, cursorID)
+	order := 1
+	if desc {
+		order = -1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: key, Value: order}, {Key: "_id", Value: order}}).
+		SetLimit(int64(q.Limit))
+
+	recipes, err := s.findAll(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if q.Cursor != nil && q.Cursor.Backward {
+		reverseRecipes(recipes)
+	}
+	return recipes, nil
+}
+
+func (s *mongoGateway) Count(ctx context.Context, filter *recipe.Filter) (int64, error) {
+	n, err := s.collection.CountDocuments(ctx, mongoFilter(filter))
+	return n, mongoError(err)
+}
+
+func (s *mongoGateway) Facets(ctx context.Context, filter *recipe.Filter) (*recipe.Facets, error) {
+	cursor, err := s.collection.Aggregate(ctx, mongoFacetsPipeline(filter))
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	var groups []mongoFacetGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, mongoError(err)
+	}
+	return mongoFacets(groups), nil
+}
+
+func (s *mongoGateway) GetByID(ctx context.Context, id string) (*recipe.Recipe, error) {
+	oid, err := mongoObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+	doc := &mongoDocument{}
+	if err := s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(doc); err != nil {
+		return nil, mongoError(err)
+	}
+	return doc.toRecipe(), nil
+}
+
+func (s *mongoGateway) DeleteByID(ctx context.Context, id string) error {
+	oid, err := mongoObjectID(id)
+	if err != nil {
+		return err
+	}
+	res, err := s.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return mongoError(err)
+	}
+	if res.DeletedCount == 0 {
+		return recipe.ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoGateway) Store(ctx context.Context, r *recipe.Recipe) error {
+	r.Version = 1
+	res, err := s.collection.InsertOne(ctx, newMongoDocument(r))
+	if err != nil {
+		return mongoError(err)
+	}
+	r.ID = res.InsertedID
+	return nil
+}
+
+// updateByID applies the change to the recipe
+func (s *mongoGateway) updateByID(ctx context.Context, id interface{}, change interface{}) error {
+	oid, err := mongoObjectID(id)
+	if err != nil {
+		return err
+	}
+	res, err := s.collection.UpdateByID(ctx, oid, change)
+	if err != nil {
+		return mongoError(err)
+	}
+	if res.MatchedCount == 0 {
+		return recipe.ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoGateway) Update(ctx context.Context, r *recipe.Recipe) error {
+	oid, err := mongoObjectID(r.ID)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"_id": oid}
+	if r.Version != 0 {
+		query["version"] = r.Version
+	}
+	opts := options.FindOneAndUpdate().
+		SetProjection(mongoKept()).
+		SetReturnDocument(options.After)
+
+	var updated mongoVersion
+	err = s.collection.FindOneAndUpdate(ctx, query, mongoUpdate(r), opts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) && r.Version != 0 {
+		// Tell the stale version from the missing recipe
+		if n, cerr := s.collection.CountDocuments(ctx, bson.M{"_id": oid}); cerr == nil && n > 0 {
+			return fmt.Errorf("%w: version %d is stale", recipe.ErrConflict, r.Version)
+		}
+	}
+	if err != nil {
+		return mongoError(err)
+	}
+	updated.restore(r)
+	return nil
+}
+
+func (s *mongoGateway) Delete(ctx context.Context, r *recipe.Recipe) error {
+	oid, err := mongoObjectID(r.ID)
+	if err != nil {
+		return err
+	}
+	return s.DeleteByID(ctx, oid.Hex())
+}
+
+func (s *mongoGateway) Search(ctx context.Context, q *recipe.SearchQuery) (*recipe.SearchResult, error) {
+	if q.Fuzziness != 0 {
+		return s.scanSearch(ctx, q)
+	}
+
+	match, score, err := mongoSearch(q)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$addFields": bson.M{"score": score}},
+		bson.M{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}},
+		bson.M{"$skip": q.Offset},
+		bson.M{"$limit": q.Limit},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	var docs []*mongoHit
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, mongoError(err)
+	}
+	total, err := s.collection.CountDocuments(ctx, match)
+	if err != nil {
+		return nil, mongoError(err)
+	}
+
+	res := &recipe.SearchResult{Hits: mongoHits(docs), Total: total}
+	if res.Total == 0 && q.Suggestive() {
+		if res.Suggestions, err = s.suggest(ctx, q.Text); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// words returns the distinct words of the names. The distinct values are
+// read from the index of nameWords, so their number grows with the
+// vocabulary rather than with the collection
+func (s *mongoGateway) words(ctx context.Context) ([]string, error) {
+	values, err := s.collection.Distinct(ctx, "nameWords", bson.M{})
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	words := make([]string, 0, len(values))
+	for _, v := range values {
+		if word, ok := v.(string); ok {
+			words = append(words, word)
+		}
+	}
+	return words, nil
+}
+
+// suggest returns the words of the names close to the text, counted over
+// the names using them only
+func (s *mongoGateway) suggest(ctx context.Context, text string) ([]string, error) {
+	words, err := s.words(ctx)
+	if err != nil {
+		return nil, err
+	}
+	near := recipe.CloseWords(text, words, recipe.FuzzyAuto)
+	if len(near) == 0 {
+		return nil, nil
+	}
+	cursor, err := s.collection.Aggregate(ctx, mongoVocabularyPipeline(near))
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	var groups []mongoWordCount
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, mongoError(err)
+	}
+	return mongoVocabulary(groups).Suggest(text), nil
+}
+
+// scanSearch runs the fuzzy search, which MongoDB cannot, over the names
+// with a word close to the text and reads the recipes of the page
+func (s *mongoGateway) scanSearch(ctx context.Context, q *recipe.SearchQuery) (*recipe.SearchResult, error) {
+	words, err := s.words(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, err := s.findAll(ctx, mongoFuzzyQuery(q, words), options.Find().SetProjection(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	res, err := matchRecipes(names, q)
+	if err != nil {
+		return nil, err
+	}
+	if res.Total == 0 && q.Suggestive() {
+		res.Suggestions, err = s.suggest(ctx, q.Text)
+		return res, err
+	}
+	if len(res.Hits) == 0 {
+		return res, nil
+	}
+
+	ids := make(bson.A, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		oid, err := mongoObjectID(hit.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, oid)
+	}
+	recipes, err := s.findAll(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	res.Hits = fillHits(res.Hits, recipes)
+	return res, nil
+}
+
+func (s *mongoGateway) ApplyRating(ctx context.Context, id string, score uint8) error {
+	return s.updateByID(ctx, id, mongoRating(score))
+}
+
+func (s *mongoGateway) AddImage(ctx context.Context, id string, img *recipe.Image) error {
+	oid, err := mongoObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	query, change := mongoAddImage(img)
+	query["_id"] = oid
+	res, err := s.collection.UpdateOne(ctx, query, change)
+	if err != nil {
+		return mongoError(err)
+	}
+	if res.MatchedCount == 0 {
+		// Tell the recipe with all the images from the missing one
+		if n, cerr := s.collection.CountDocuments(ctx, bson.M{"_id": oid}); cerr == nil && n > 0 {
+			return recipe.ErrTooManyImages
+		}
+		return recipe.ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoGateway) RemoveImage(ctx context.Context, id, imageID string) (*recipe.Image, error) {
+	oid, err := mongoObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetProjection(bson.M{"images": 1}).
+		SetReturnDocument(options.Before)
+
+	var before mongoVersion
+	query := bson.M{"_id": oid, "images.id": imageID}
+	err = s.collection.FindOneAndUpdate(ctx, query, mongoRemoveImage(imageID), opts).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if n, cerr := s.collection.CountDocuments(ctx, bson.M{"_id": oid}); cerr == nil && n > 0 {
+			return nil, recipe.ErrImageNotFound
+		}
+	}
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	return before.image(imageID), nil
+}
+
+func (s *mongoGateway) Suggest(ctx context.Context, q *recipe.SuggestQuery) ([]*recipe.Suggestion, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"name": 1}).
+		SetSort(bson.D{{Key: "popularity", Value: -1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(q.Limit))
+	recipes, err := s.findAll(ctx, mongoSuggest(q), opts)
+	if err != nil {
+		return nil, err
+	}
+	return recipe.Suggestions(recipes), nil
+}
+
+func (s *mongoGateway) Terms(ctx context.Context, kind recipe.TermKind) ([]*recipe.Term, error) {
+	cursor, err := s.taxonomy.Find(ctx, bson.M{"kind": kind})
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	terms := []*recipe.Term{}
+	if err := cursor.All(ctx, &terms); err != nil {
+		return nil, mongoError(err)
+	}
+
+	cursor, err = s.collection.Aggregate(ctx, mongoTermCountsPipeline(kind))
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	var groups []mongoTermCount
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, mongoError(err)
+	}
+	return mongoTermCounts(terms, groups), nil
+}
+
+func (s *mongoGateway) GetTerm(ctx context.Context, kind recipe.TermKind, slug string) (*recipe.Term, error) {
+	t := &recipe.Term{}
+	if err := s.taxonomy.FindOne(ctx, mongoTermQuery(kind, slug)).Decode(t); err != nil {
+		return nil, termError(mongoError(err))
+	}
+	n, err := s.collection.CountDocuments(ctx, bson.M{mongoTermFields[kind]: slug})
+	if err != nil {
+		return nil, mongoError(err)
+	}
+	t.Count = n
+	return t, nil
+}
+
+func (s *mongoGateway) StoreTerm(ctx context.Context, t *recipe.Term) error {
+	_, err := s.taxonomy.InsertOne(ctx, t)
+	return mongoError(err)
+}
+
+// UpdateTerm changes the term and the recipes one after another, the
+// recipes keep the old slug if the second change fails
+func (s *mongoGateway) UpdateTerm(ctx context.Context, slug string, t *recipe.Term) error {
+	res, err := s.taxonomy.ReplaceOne(ctx, mongoTermQuery(t.Kind, slug), t)
+	if err != nil {
+		return mongoError(err)
+	}
+	if res.MatchedCount == 0 {
+		return recipe.ErrTermNotFound
+	}
+	if t.Slug == slug {
+		return nil
+	}
+	query, update := mongoReplaceTerm(t.Kind, slug, t.Slug)
+	_, err = s.collection.UpdateMany(ctx, query, update)
+	return mongoError(err)
+}
+
+func (s *mongoGateway) DeleteTerm(ctx context.Context, kind recipe.TermKind, slug string) error {
+	res, err := s.taxonomy.DeleteOne(ctx, mongoTermQuery(kind, slug))
+	if err != nil {
+		return mongoError(err)
+	}
+	if res.DeletedCount == 0 {
+		return recipe.ErrTermNotFound
+	}
+	query, update := mongoReplaceTerm(kind, slug, "")
+	_, err = s.collection.UpdateMany(ctx, query, update)
+	return mongoError(err)
+}
@@ -0,0 +1,90 @@
+package gateways
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"time"
+)
+
+// MongoOptions are the connection options shared by the MongoDB gateways.
+// When URI is given it is used as the base, and the other non-empty
+// options override it
+type MongoOptions struct {
+	URI string
+
+	Server   string
+	Port     string
+	Username string
+	Password string
+
+	// AuthSource is the database to authenticate against, the one of the URI
+	// or "admin" by default
+	AuthSource string
+	// AuthMechanism is one of SCRAM-SHA-1, SCRAM-SHA-256, MONGODB-X509 and etc.
+	AuthMechanism string
+
+	TLS       bool
+	TLSCAFile string
+
+	ReplicaSet string
+	// ReadPreference is one of primary, primaryPreferred, secondary,
+	// secondaryPreferred and nearest
+	ReadPreference string
+	// WriteConcern is "majority" or the number of the acknowledging nodes
+	WriteConcern string
+
+	MaxPoolSize    uint64
+	ConnectTimeout time.Duration
+	SocketTimeout  time.Duration
+
+	Database   string
+	Collection string
+	// TaxonomyCollection keeps the taxonomy terms
+	TaxonomyCollection string
+}
+
+// address returns the host:port of the server or the empty string
+func (o *MongoOptions) address() string {
+	if o.Server == "" {
+		return ""
+	}
+	if o.Port == "" {
+		return o.Server
+	}
+	return net.JoinHostPort(o.Server, o.Port)
+}
+
+// tlsConfig returns the TLS configuration trusting the custom CA if given
+func (o *MongoOptions) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if o.TLSCAFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := ioutil.ReadFile(o.TLSCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading the CA file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in the CA file: %s", o.TLSCAFile)
+	}
+	cfg.RootCAs = pool
+	return cfg, nil
+}
+
+// writeConcern returns the write concern as "majority" or the number of nodes
+func (o *MongoOptions) writeConcern() (majority bool, w int, err error) {
+	if o.WriteConcern == "majority" {
+		return true, 0, nil
+	}
+	w, err = strconv.Atoi(o.WriteConcern)
+	if err != nil || w < 0 {
+		return false, 0, fmt.Errorf("invalid write concern: %s", o.WriteConcern)
+	}
+	return false, w, nil
+}
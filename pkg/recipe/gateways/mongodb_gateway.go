@@ -1,6 +1,12 @@
 package gateways
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -8,81 +14,641 @@ import (
 
 type mgoGateway struct {
 	session    *mgo.Session
-	db         *mgo.Database
-	collection *mgo.Collection
+	database   string
+	collection string
+	taxonomy   string
 }
 
 // NewMongoDbGateway create a storage gateway to the MongoDB
-func NewMongoDbGateway(server, port, username, password, database, collection string) (recipe.StorageGateway, error) {
-	session, err := mgo.Dial(server)
+func NewMongoDbGateway(opts MongoOptions) (recipe.StorageGateway, error) {
+	info, err := mgoDialInfo(&opts)
+	if err != nil {
+		return nil, err
+	}
+	session, err := mgo.DialWithInfo(info)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := mgoMode(opts.ReadPreference)
 	if err != nil {
+		session.Close()
 		return nil, err
 	}
-	session.SetMode(mgo.Monotonic, true)
-	db := session.DB(database)
+	session.SetMode(mode, true)
+
+	// The full-text search needs the text index, the suggestions need the
+	// index of the name words
+	indexes := []mgo.Index{
+		{Key: []string{"$text:name"}, Background: true},
+		{Key: []string{"nameWords"}, Background: true},
+	}
+	for _, index := range indexes {
+		if err := session.DB(opts.Database).C(opts.Collection).EnsureIndex(index); err != nil {
+			session.Close()
+			return nil, err
+		}
+	}
+	// The slugs of the terms are unique by kind
+	termIndex := mgo.Index{Key: []string{"kind", "slug"}, Unique: true}
+	if err := session.DB(opts.Database).C(opts.TaxonomyCollection).EnsureIndex(termIndex); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	if opts.WriteConcern != "" {
+		majority, w, err := opts.writeConcern()
+		if err != nil {
+			session.Close()
+			return nil, err
+		}
+		safe := &mgo.Safe{W: w}
+		if majority {
+			safe = &mgo.Safe{WMode: "majority"}
+		}
+		session.SetSafe(safe)
+	}
+	if opts.SocketTimeout > 0 {
+		session.SetSocketTimeout(opts.SocketTimeout)
+	}
+
 	gw := &mgoGateway{
 		session:    session,
-		db:         db,
-		collection: db.C(collection),
+		database:   opts.Database,
+		collection: opts.Collection,
+		taxonomy:   opts.TaxonomyCollection,
+	}
+	if err := gw.backfill(context.Background()); err != nil {
+		session.Close()
+		return nil, err
 	}
 	return gw, nil
 }
 
-func (s *mgoGateway) GetRange(start, limit uint64) ([]*recipe.Recipe, error) {
-	var recipes []*recipe.Recipe
-	err := s.collection.Find(nil).Skip(int(start)).Limit(int(limit)).All(&recipes)
-	return recipes, err
+// backfill sets the fields of the suggestions to the documents stored
+// without them, so the older recipes are suggested too
+func (s *mgoGateway) backfill(ctx context.Context) error {
+	return s.withCollection(ctx, func(c *mgo.Collection) error {
+		iter := c.Find(mongoBackfillQuery()).Select(mongoBackfillFields()).Iter()
+		doc := &mongoDocument{}
+		for iter.Next(doc) {
+			if err := c.UpdateId(doc.ID, mongoBackfill(doc)); err != nil {
+				iter.Close()
+				return err
+			}
+			doc = &mongoDocument{}
+		}
+		return iter.Close()
+	})
 }
 
-func (s *mgoGateway) GetByID(id string) (*recipe.Recipe, error) {
-	recipe := &recipe.Recipe{}
-	query := bson.M{"_id": bson.ObjectIdHex(id)}
-	if err := s.collection.Find(query).One(&recipe); err != nil {
-		return nil, err
+// mgoDialInfo builds the mgo dial info from the connection options
+func mgoDialInfo(opts *MongoOptions) (*mgo.DialInfo, error) {
+	info := &mgo.DialInfo{}
+	if opts.URI != "" {
+		var err error
+		if info, err = mgo.ParseURL(opts.URI); err != nil {
+			return nil, err
+		}
+	}
+
+	if addr := opts.address(); addr != "" {
+		info.Addrs = []string{addr}
+	}
+	// mgo authenticates against the database of the URI or "admin" unless
+	// the source is given
+	if opts.Username != "" {
+		info.Username = opts.Username
+		info.Password = opts.Password
+	}
+	if opts.AuthSource != "" {
+		info.Source = opts.AuthSource
+	}
+	if opts.AuthMechanism != "" {
+		info.Mechanism = opts.AuthMechanism
+	}
+	if opts.ReplicaSet != "" {
+		info.ReplicaSetName = opts.ReplicaSet
+	}
+	if opts.MaxPoolSize > 0 {
+		info.PoolLimit = int(opts.MaxPoolSize)
+	}
+	info.Timeout = 10 * time.Second
+	if opts.ConnectTimeout > 0 {
+		info.Timeout = opts.ConnectTimeout
 	}
-	return recipe, nil
-}
 
-func (s *mgoGateway) DeleteByID(id string) error {
-	query := bson.M{"_id": bson.ObjectIdHex(id)}
-	return s.collection.Remove(query)
+	if opts.TLS {
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		dialer := &net.Dialer{Timeout: info.Timeout}
+		info.DialServer = func(addr *mgo.ServerAddr) (net.Conn, error) {
+			return tls.DialWithDialer(dialer, "tcp", addr.String(), tlsConfig)
+		}
+	}
+	return info, nil
 }
 
-func (s *mgoGateway) Store(r *recipe.Recipe) error {
-	return s.collection.Insert(r)
+// mgoMode converts the read preference to the mgo session mode
+func mgoMode(readPreference string) (mgo.Mode, error) {
+	switch readPreference {
+	case "":
+		return mgo.Monotonic, nil
+	case "primary":
+		return mgo.Primary, nil
+	case "primaryPreferred":
+		return mgo.PrimaryPreferred, nil
+	case "secondary":
+		return mgo.Secondary, nil
+	case "secondaryPreferred":
+		return mgo.SecondaryPreferred, nil
+	case "nearest":
+		return mgo.Nearest, nil
+	}
+	return 0, fmt.Errorf("invalid read preference: %s", readPreference)
 }
 
-func (s *mgoGateway) Update(r *recipe.Recipe) error {
-	var ID string
-	switch v := r.ID.(type) {
+// mgoObjectID converts the recipe ID to the MongoDB ObjectId
+func mgoObjectID(id interface{}) (bson.ObjectId, error) {
+	switch v := id.(type) {
 	case string:
-		ID = v
+		if bson.IsObjectIdHex(v) {
+			return bson.ObjectIdHex(v), nil
+		}
 	case bson.ObjectId:
-		ID = v.Hex()
+		if v.Valid() {
+			return v, nil
+		}
 	}
+	return "", fmt.Errorf("%w: %v", recipe.ErrInvalidID, id)
+}
 
-	query := bson.M{"_id": bson.ObjectIdHex(ID)}
-	change := bson.M{"$set": bson.M{
-		"name":          r.Name,
-		"prepTime":      r.PrepTime,
-		"difficulty":    r.Difficulty,
-		"vegetarian":    r.Vegetarian,
-		"averageRating": r.AverageRating,
-		"ratingsCount":  r.RatingsCount,
-	}}
-	return s.collection.Update(query, change)
+// mgoError converts the mgo errors to the recipe errors
+func mgoError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == mgo.ErrNotFound {
+		return recipe.ErrNotFound
+	}
+	if mgo.IsDup(err) {
+		return fmt.Errorf("%w: %v", recipe.ErrConflict, err)
+	}
+	if qerr, ok := err.(*mgo.QueryError); ok && (qerr.Code == 2 || qerr.Code == 51091) {
+		// BadValue or invalid regular expression
+		return fmt.Errorf("%w: %v", recipe.ErrValidation, err)
+	}
+	return err
 }
 
-func (s *mgoGateway) Delete(r *recipe.Recipe) error {
-	query := bson.M{"_id": bson.ObjectIdHex(r.ID.(string))}
-	return s.collection.Remove(query)
+// withCollection runs the operation on the recipes collection, see withDatabase
+func (s *mgoGateway) withCollection(ctx context.Context, op func(c *mgo.Collection) error) error {
+	return s.withDatabase(ctx, func(db *mgo.Database) error {
+		return op(db.C(s.collection))
+	})
 }
 
-func (s *mgoGateway) Search(pattern string) ([]*recipe.Recipe, error) {
-	var recipes []*recipe.Recipe
-	regex := bson.M{"$regex": bson.RegEx{Pattern: pattern}}
-	if err := s.collection.Find(bson.M{"name": regex}).All(&recipes); err != nil {
+// withDatabase runs the operation on a copy of the session. mgo knows
+// nothing about contexts, so the deadline becomes the socket timeout, and
+// the operation is left to finish in the background when the context is
+// done: mgo cannot cancel the operation already running, the mongo-driver
+// gateway does. The copy is closed by the operation itself, closing it
+// earlier would panic on the next step of the operation.
+//
+// The operation may still run when the context error is returned, so the
+// caller reads what the operation writes only when no error is returned
+func (s *mgoGateway) withDatabase(ctx context.Context, op func(db *mgo.Database) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	session := s.session.Copy()
+	if deadline, ok := ctx.Deadline(); ok {
+		session.SetSocketTimeout(time.Until(deadline))
+	}
+	return mgoError(withContext(ctx, func() error {
+		defer session.Close()
+		return op(session.DB(s.database))
+	}))
+}
+
+// withContext runs the operation in the background and waits for it until
+// the context is done. The nil error is returned after the operation ends
+func withContext(ctx context.Context, op func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- op()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *mgoGateway) GetRange(ctx context.Context, start, limit uint64) ([]*recipe.Recipe, error) {
+	var docs []*mongoDocument
+	err := s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Find(nil).Skip(int(start)).Limit(int(limit)).All(&docs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mongoRecipes(docs), nil
+}
+
+func (s *mgoGateway) GetPage(ctx context.Context, q *recipe.RangeQuery) ([]*recipe.Recipe, error) {
+	var cursorID interface{}
+	if q.Cursor != nil {
+		oid, err := mgoObjectID(q.Cursor.ID)
+		if err != nil {
+			return nil, err
+		}
+		cursorID = oid
+	}
+
+	filter, key, desc := mongoRange(q, cursorID)
+	if desc {
+		key = "-" + key
+	}
+	idKey := "_id"
+	if desc {
+		idKey = "-_id"
+	}
+
+	var docs []*mongoDocument
+	err := s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Find(filter).Sort(key, idKey).Limit(q.Limit).All(&docs)
+	})
+	if err != nil {
 		return nil, err
 	}
+	recipes := mongoRecipes(docs)
+	if q.Cursor != nil && q.Cursor.Backward {
+		reverseRecipes(recipes)
+	}
 	return recipes, nil
 }
+
+func (s *mgoGateway) Count(ctx context.Context, filter *recipe.Filter) (int64, error) {
+	var n int
+	err := s.withCollection(ctx, func(c *mgo.Collection) error {
+		var err error
+		n, err = c.Find(mongoFilter(filter)).Count()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+func (s *mgoGateway) Facets(ctx context.Context, filter *recipe.Filter) (*recipe.Facets, error) {
+	var groups []mongoFacetGroup
+	err := s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Pipe(mongoFacetsPipeline(filter)).All(&groups)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mongoFacets(groups), nil
+}
+
+func (s *mgoGateway) GetByID(ctx context.Context, id string) (*recipe.Recipe, error) {
+	oid, err := mgoObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+	doc := &mongoDocument{}
+	err = s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.FindId(oid).One(doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc.toRecipe(), nil
+}
+
+func (s *mgoGateway) DeleteByID(ctx context.Context, id string) error {
+	oid, err := mgoObjectID(id)
+	if err != nil {
+		return err
+	}
+	return s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.RemoveId(oid)
+	})
+}
+
+func (s *mgoGateway) Store(ctx context.Context, r *recipe.Recipe) error {
+	// mgo does not return the generated ID, so it is generated here
+	if r.ID == nil {
+		r.ID = bson.NewObjectId()
+	}
+	r.Version = 1
+	doc := newMongoDocument(r)
+	return s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Insert(doc)
+	})
+}
+
+func (s *mgoGateway) Update(ctx context.Context, r *recipe.Recipe) error {
+	oid, err := mgoObjectID(r.ID)
+	if err != nil {
+		return err
+	}
+
+	query := bson.M{"_id": oid}
+	if r.Version != 0 {
+		query["version"] = r.Version
+	}
+	version := r.Version
+	change := mgo.Change{Update: mongoUpdate(r), ReturnNew: true}
+	var updated mongoVersion
+	err = s.withCollection(ctx, func(c *mgo.Collection) error {
+		_, err := c.Find(query).Select(mongoKept()).Apply(change, &updated)
+		if err == mgo.ErrNotFound && version != 0 {
+			// Tell the stale version from the missing recipe
+			if n, cerr := c.FindId(oid).Count(); cerr == nil && n > 0 {
+				return fmt.Errorf("%w: version %d is stale", recipe.ErrConflict, version)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	updated.restore(r)
+	return nil
+}
+
+func (s *mgoGateway) Delete(ctx context.Context, r *recipe.Recipe) error {
+	oid, err := mgoObjectID(r.ID)
+	if err != nil {
+		return err
+	}
+	return s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.RemoveId(oid)
+	})
+}
+
+func (s *mgoGateway) Search(ctx context.Context, q *recipe.SearchQuery) (*recipe.SearchResult, error) {
+	if q.Fuzziness != 0 {
+		return s.scanSearch(ctx, q)
+	}
+
+	match, score, err := mongoSearch(q)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$addFields": bson.M{"score": score}},
+		{"$sort": bson.D{{Name: "score", Value: -1}, {Name: "name", Value: 1}, {Name: "_id", Value: 1}}},
+		{"$skip": q.Offset},
+		{"$limit": q.Limit},
+	}
+
+	var docs []*mongoHit
+	var total int
+	err = s.withCollection(ctx, func(c *mgo.Collection) error {
+		if err := c.Pipe(pipeline).All(&docs); err != nil {
+			return err
+		}
+		var err error
+		total, err = c.Find(match).Count()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &recipe.SearchResult{Hits: mongoHits(docs), Total: int64(total)}
+	if res.Total == 0 && q.Suggestive() {
+		if res.Suggestions, err = s.suggest(ctx, q.Text); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// words returns the distinct words of the names. The distinct values are
+// read from the index of nameWords, so their number grows with the
+// vocabulary rather than with the collection
+func (s *mgoGateway) words(ctx context.Context) ([]string, error) {
+	var words []string
+	err := s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Find(nil).Distinct("nameWords", &words)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// suggest returns the words of the names close to the text, counted over
+// the names using them only
+func (s *mgoGateway) suggest(ctx context.Context, text string) ([]string, error) {
+	words, err := s.words(ctx)
+	if err != nil {
+		return nil, err
+	}
+	near := recipe.CloseWords(text, words, recipe.FuzzyAuto)
+	if len(near) == 0 {
+		return nil, nil
+	}
+	var groups []mongoWordCount
+	err = s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Pipe(mongoVocabularyPipeline(near)).All(&groups)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mongoVocabulary(groups).Suggest(text), nil
+}
+
+// scanSearch runs the fuzzy search, which MongoDB cannot, over the names
+// with a word close to the text and reads the recipes of the page
+func (s *mgoGateway) scanSearch(ctx context.Context, q *recipe.SearchQuery) (*recipe.SearchResult, error) {
+	words, err := s.words(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []*recipe.Recipe
+	err = s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Find(mongoFuzzyQuery(q, words)).Select(bson.M{"name": 1}).All(&names)
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := matchRecipes(names, q)
+	if err != nil {
+		return nil, err
+	}
+	if res.Total == 0 && q.Suggestive() {
+		res.Suggestions, err = s.suggest(ctx, q.Text)
+		return res, err
+	}
+	if len(res.Hits) == 0 {
+		return res, nil
+	}
+
+	ids := make([]bson.ObjectId, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		oid, err := mgoObjectID(hit.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, oid)
+	}
+	var docs []*mongoDocument
+	err = s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Find(bson.M{"_id": bson.M{"$in": ids}}).All(&docs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Hits = fillHits(res.Hits, mongoRecipes(docs))
+	return res, nil
+}
+
+func (s *mgoGateway) ApplyRating(ctx context.Context, id string, score uint8) error {
+	oid, err := mgoObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	change := mongoRating(score)
+	return s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.UpdateId(oid, change)
+	})
+}
+
+func (s *mgoGateway) AddImage(ctx context.Context, id string, img *recipe.Image) error {
+	oid, err := mgoObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	query, change := mongoAddImage(img)
+	query["_id"] = oid
+	return s.withCollection(ctx, func(c *mgo.Collection) error {
+		err := c.Update(query, change)
+		if err == mgo.ErrNotFound {
+			// Tell the recipe with all the images from the missing one
+			if n, cerr := c.FindId(oid).Count(); cerr == nil && n > 0 {
+				return recipe.ErrTooManyImages
+			}
+		}
+		return err
+	})
+}
+
+func (s *mgoGateway) RemoveImage(ctx context.Context, id, imageID string) (*recipe.Image, error) {
+	oid, err := mgoObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var before mongoVersion
+	change := mgo.Change{Update: mongoRemoveImage(imageID)}
+	err = s.withCollection(ctx, func(c *mgo.Collection) error {
+		_, err := c.Find(bson.M{"_id": oid, "images.id": imageID}).Select(bson.M{"images": 1}).Apply(change, &before)
+		if err == mgo.ErrNotFound {
+			if n, cerr := c.FindId(oid).Count(); cerr == nil && n > 0 {
+				return recipe.ErrImageNotFound
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return before.image(imageID), nil
+}
+
+func (s *mgoGateway) Suggest(ctx context.Context, q *recipe.SuggestQuery) ([]*recipe.Suggestion, error) {
+	var recipes []*recipe.Recipe
+	err := s.withCollection(ctx, func(c *mgo.Collection) error {
+		return c.Find(mongoSuggest(q)).
+			Select(bson.M{"name": 1}).
+			Sort("-popularity", "name", "_id").
+			Limit(q.Limit).
+			All(&recipes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recipe.Suggestions(recipes), nil
+}
+
+func (s *mgoGateway) Terms(ctx context.Context, kind recipe.TermKind) ([]*recipe.Term, error) {
+	var terms []*recipe.Term
+	var groups []mongoTermCount
+	err := s.withDatabase(ctx, func(db *mgo.Database) error {
+		if err := db.C(s.taxonomy).Find(bson.M{"kind": kind}).All(&terms); err != nil {
+			return err
+		}
+		return db.C(s.collection).Pipe(mongoTermCountsPipeline(kind)).All(&groups)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mongoTermCounts(terms, groups), nil
+}
+
+func (s *mgoGateway) GetTerm(ctx context.Context, kind recipe.TermKind, slug string) (*recipe.Term, error) {
+	t := &recipe.Term{}
+	err := s.withDatabase(ctx, func(db *mgo.Database) error {
+		if err := db.C(s.taxonomy).Find(mongoTermQuery(kind, slug)).One(t); err != nil {
+			return err
+		}
+		n, err := db.C(s.collection).Find(bson.M{mongoTermFields[kind]: slug}).Count()
+		t.Count = int64(n)
+		return err
+	})
+	if err != nil {
+		return nil, termError(err)
+	}
+	return t, nil
+}
+
+func (s *mgoGateway) StoreTerm(ctx context.Context, t *recipe.Term) error {
+	term := *t
+	return s.withDatabase(ctx, func(db *mgo.Database) error {
+		return db.C(s.taxonomy).Insert(&term)
+	})
+}
+
+// UpdateTerm changes the term and the recipes one after another, the
+// recipes keep the old slug if the second change fails
+func (s *mgoGateway) UpdateTerm(ctx context.Context, slug string, t *recipe.Term) error {
+	term := *t
+	err := s.withDatabase(ctx, func(db *mgo.Database) error {
+		if err := db.C(s.taxonomy).Update(mongoTermQuery(term.Kind, slug), &term); err != nil {
+			return err
+		}
+		if term.Slug == slug {
+			return nil
+		}
+		query, update := mongoReplaceTerm(term.Kind, slug, term.Slug)
+		_, err := db.C(s.collection).UpdateAll(query, update)
+		return err
+	})
+	return termError(err)
+}
+
+func (s *mgoGateway) DeleteTerm(ctx context.Context, kind recipe.TermKind, slug string) error {
+	err := s.withDatabase(ctx, func(db *mgo.Database) error {
+		if err := db.C(s.taxonomy).Remove(mongoTermQuery(kind, slug)); err != nil {
+			return err
+		}
+		query, update := mongoReplaceTerm(kind, slug, "")
+		_, err := db.C(s.collection).UpdateAll(query, update)
+		return err
+	})
+	return termError(err)
+}
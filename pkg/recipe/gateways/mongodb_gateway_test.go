@@ -0,0 +1,36 @@
+package gateways
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("withContext", func() {
+	It("should leave the canceled operation to finish all its steps", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		resume, closed := make(chan struct{}), make(chan []string, 1)
+
+		err := withContext(ctx, func() error {
+			// The steps are seen by the deferred close only
+			steps := []string{"write the term"}
+			defer func() { closed <- steps }()
+
+			cancel()
+			<-resume
+			steps = append(steps, "update the recipes")
+			return nil
+		})
+		Expect(err).To(MatchError(context.Canceled))
+		close(resume)
+		Eventually(closed).Should(Receive(Equal([]string{"write the term", "update the recipes"})))
+	})
+
+	It("should return the error of the operation", func() {
+		err := withContext(context.Background(), func() error {
+			return context.DeadlineExceeded
+		})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
@@ -0,0 +1,117 @@
+package gateways
+
+import (
+	"strings"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// rangeDesc tells whether the storage should be walked in the descending
+// order to get the range. The backward cursor walks against the sort order
+func rangeDesc(q *recipe.RangeQuery) bool {
+	return q.Sort.Desc != (q.Cursor != nil && q.Cursor.Backward)
+}
+
+// reverseRecipes reverses the recipes in place, it turns the recipes
+// walked against the sort order back into it
+func reverseRecipes(recipes []*recipe.Recipe) {
+	for i, j := 0, len(recipes)-1; i < j; i, j = i+1, j-1 {
+		recipes[i], recipes[j] = recipes[j], recipes[i]
+	}
+}
+
+// compareValues compares the sort values of the same type
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case float64:
+		bv := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	case int64:
+		bv := b.(int64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	}
+	return 0
+}
+
+// pageHits sorts all the hits by relevance and cuts the page of the query
+func pageHits(hits []*recipe.SearchHit, q *recipe.SearchQuery) *recipe.SearchResult {
+	recipe.SortHits(hits)
+	res := &recipe.SearchResult{Total: int64(len(hits))}
+	if q.Offset < len(hits) {
+		hits = hits[q.Offset:]
+		if len(hits) > q.Limit {
+			hits = hits[:q.Limit]
+		}
+		res.Hits = hits
+	}
+	return res
+}
+
+// scanSearch runs the search query over all the recipes, see matchRecipes,
+// and suggests the words of their names when nothing is found
+func scanSearch(recipes []*recipe.Recipe, q *recipe.SearchQuery) (*recipe.SearchResult, error) {
+	res, err := matchRecipes(recipes, q)
+	if err != nil {
+		return nil, err
+	}
+	if res.Total == 0 && q.Suggestive() {
+		res.Suggestions = suggestWords(recipes, q.Text)
+	}
+	return res, nil
+}
+
+// matchRecipes runs the search query over the recipes with the reference
+// semantics of recipe.SearchQuery.Matcher, for the storages and queries
+// which cannot be run natively. The recipes may have the names only
+func matchRecipes(recipes []*recipe.Recipe, q *recipe.SearchQuery) (*recipe.SearchResult, error) {
+	match, err := q.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []*recipe.SearchHit
+	for _, r := range recipes {
+		if score, ok := match(r); ok {
+			hits = append(hits, &recipe.SearchHit{Recipe: r, Score: score})
+		}
+	}
+	return pageHits(hits, q), nil
+}
+
+// suggestWords returns the words of the recipe names close to the text
+func suggestWords(recipes []*recipe.Recipe, text string) []string {
+	vocabulary := recipe.Vocabulary{}
+	for _, r := range recipes {
+		vocabulary.Add(r.Name)
+	}
+	return vocabulary.Suggest(text)
+}
+
+// fillHits replaces the recipes of the hits found by name with the full
+// ones, the hits of the recipes deleted since are dropped
+func fillHits(hits []*recipe.SearchHit, recipes []*recipe.Recipe) []*recipe.SearchHit {
+	byID := make(map[string]*recipe.Recipe, len(recipes))
+	for _, r := range recipes {
+		byID[r.IDHex()] = r
+	}
+	filled := hits[:0]
+	for _, hit := range hits {
+		if r, ok := byID[hit.IDHex()]; ok {
+			hit.Recipe = r
+			filled = append(filled, hit)
+		}
+	}
+	return filled
+}
@@ -0,0 +1,53 @@
+package recipe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxImages is the maximal number of the images of the recipe
+const MaxImages = 10
+
+// ErrImageNotFound is returned when the recipe has no such image
+var ErrImageNotFound = errors.New("image not found")
+
+// ErrTooManyImages is returned when the recipe has MaxImages already
+var ErrTooManyImages = fmt.Errorf("%w: more than %d images", ErrValidation, MaxImages)
+
+// Image is the picture of the recipe resized into the variants, such as
+// the thumbnail. The images are added and removed by the image endpoints
+// only, the ones given with the recipe are ignored
+type Image struct {
+	ID       string                  `json:"id" bson:"id"`
+	Variants map[string]ImageVariant `json:"variants" bson:"variants"`
+}
+
+// ImageVariant is the resized image kept in the blob store by the key
+type ImageVariant struct {
+	URL         string `json:"url" bson:"url"`
+	Key         string `json:"-" bson:"key"`
+	ContentType string `json:"contentType" bson:"contentType"`
+	Width       int    `json:"width" bson:"width"`
+	Height      int    `json:"height" bson:"height"`
+}
+
+// Copy returns a copy of the image
+func (i *Image) Copy() Image {
+	c := Image{ID: i.ID, Variants: make(map[string]ImageVariant, len(i.Variants))}
+	for name, v := range i.Variants {
+		c.Variants[name] = v
+	}
+	return c
+}
+
+// CopyImages returns a copy of the images
+func CopyImages(images []Image) []Image {
+	if images == nil {
+		return nil
+	}
+	c := make([]Image, len(images))
+	for i := range images {
+		c[i] = images[i].Copy()
+	}
+	return c
+}
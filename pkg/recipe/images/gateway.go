@@ -0,0 +1,49 @@
+package images
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/blob"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	log "github.com/sirupsen/logrus"
+)
+
+type imagesGateway struct {
+	recipe.StorageGateway
+	blobs blob.Store
+}
+
+// NewGateway create a storage gateway which deletes the images of the
+// recipes from the blob store together with the recipes
+func NewGateway(s recipe.StorageGateway, blobs blob.Store) recipe.StorageGateway {
+	return &imagesGateway{StorageGateway: s, blobs: blobs}
+}
+
+func (s *imagesGateway) DeleteByID(ctx context.Context, id string) error {
+	r, err := s.StorageGateway.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.StorageGateway.DeleteByID(ctx, id); err != nil {
+		return err
+	}
+	DeleteBlobs(ctx, s.blobs, r.Images...)
+	return nil
+}
+
+func (s *imagesGateway) Delete(ctx context.Context, r *recipe.Recipe) error {
+	return s.DeleteByID(ctx, r.IDHex())
+}
+
+// DeleteBlobs deletes the variants of the images from the blob store. The
+// recipe is gone already, so the failures are only logged and the blobs
+// left behind may be collected later
+func DeleteBlobs(ctx context.Context, blobs blob.Store, images ...recipe.Image) {
+	for _, img := range images {
+		for _, v := range img.Variants {
+			if err := blobs.Delete(ctx, v.Key); err != nil {
+				log.Errorf("DeleteBlobs: %s: %v", v.Key, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"golang.org/x/image/draw"
+
+	// The decoders of the accepted images
+	_ "golang.org/x/image/webp"
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Limits of the uploaded images
+const (
+	// MaxSize is the maximal size of the image file in bytes
+	MaxSize = 10 << 20
+	// MaxPixels is the maximal number of the image pixels, it keeps the
+	// small files of the huge images from taking all the memory
+	MaxPixels = 40000000
+)
+
+// ContentType is the content type of the resized images
+const ContentType = "image/jpeg"
+
+// ErrTooLarge is returned for the image exceeding the limits
+var ErrTooLarge = errors.New("image is too large")
+
+// ContentTypes are the content types of the accepted images
+var ContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Variant is the size the images are resized to
+type Variant struct {
+	Name          string
+	Width, Height int
+	// Crop tells whether the image is cropped to fill the size, otherwise
+	// it is fit into the size
+	Crop bool
+}
+
+// Variants are the sizes of the recipe images
+var Variants = []Variant{
+	{Name: "thumbnail", Width: 150, Height: 150, Crop: true},
+	{Name: "card", Width: 400, Height: 300, Crop: true},
+	{Name: "hero", Width: 1200, Height: 800},
+}
+
+// Resized is the image resized to the variant and encoded as JPEG
+type Resized struct {
+	Name          string
+	Width, Height int
+	Data          []byte
+}
+
+// Sniff returns the content type of the image data, which is not trusted
+// to the file name or the type given by the client
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	for _, accepted := range ContentTypes {
+		if contentType == accepted {
+			return contentType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unsupported image type %s", recipe.ErrValidation, contentType)
+}
+
+// Decode decodes the image after checking its type and size
+func Decode(data []byte) (image.Image, error) {
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, MaxSize)
+	}
+	if _, err := Sniff(data); err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid image: %v", recipe.ErrValidation, err)
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("%w: more than %d pixels", ErrTooLarge, MaxPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid image: %v", recipe.ErrValidation, err)
+	}
+	return img, nil
+}
+
+// Resize returns the image resized to the variant. The images are never
+// upscaled, and the transparent ones get the white background
+func Resize(img image.Image, v Variant) image.Image {
+	b := img.Bounds()
+	src := b
+	var w, h int
+	if v.Crop {
+		// Crop the centre of the image to the proportions of the variant
+		if b.Dx()*v.Height > b.Dy()*v.Width {
+			cw := b.Dy() * v.Width / v.Height
+			src.Min.X += (b.Dx() - cw) / 2
+			src.Max.X = src.Min.X + cw
+		} else {
+			ch := b.Dx() * v.Height / v.Width
+			src.Min.Y += (b.Dy() - ch) / 2
+			src.Max.Y = src.Min.Y + ch
+		}
+		w, h = v.Width, v.Height
+		if src.Dx() < w {
+			w, h = src.Dx(), src.Dy()
+		}
+	} else {
+		w, h = b.Dx(), b.Dy()
+		if w > v.Width {
+			w, h = v.Width, h*v.Width/w
+		}
+		if h > v.Height {
+			w, h = w*v.Height/h, v.Height
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Over, nil)
+	return dst
+}
+
+// Process decodes the image and resizes it to all the variants
+func Process(data []byte) ([]Resized, error) {
+	img, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	resized := make([]Resized, 0, len(Variants))
+	for _, v := range Variants {
+		dst := Resize(img, v)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		resized = append(resized, Resized{
+			Name:   v.Name,
+			Width:  dst.Bounds().Dx(),
+			Height: dst.Bounds().Dy(),
+			Data:   buf.Bytes(),
+		})
+	}
+	return resized, nil
+}
+
+// Key returns the blob key of the image variant
+func Key(recipeID, imageID, variant string) string {
+	return fmt.Sprintf("recipes/%s/%s/%s.jpg", recipeID, imageID, variant)
+}
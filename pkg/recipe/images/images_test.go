@@ -0,0 +1,87 @@
+package images_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/images"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// encodePNG returns the PNG of the image filled with the colour
+func encodePNG(w, h int, c color.Color) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	Expect(png.Encode(&buf, img)).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("Images", func() {
+	It("should sniff the content type of the images", func() {
+		contentType, err := images.Sniff(encodePNG(2, 2, color.Black))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contentType).To(Equal("image/png"))
+
+		_, err = images.Sniff([]byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>"))
+		Expect(err).To(MatchError(recipe.ErrValidation))
+		_, err = images.Decode([]byte("\x89PNG\r\n\x1a\n broken"))
+		Expect(err).To(MatchError(recipe.ErrValidation))
+	})
+
+	It("should reject the images exceeding the limits", func() {
+		_, err := images.Decode(make([]byte, images.MaxSize+1))
+		Expect(err).To(MatchError(images.ErrTooLarge))
+
+		// The header of the huge image is enough to reject it
+		huge := image.NewGray(image.Rect(0, 0, 8000, 6000))
+		var buf bytes.Buffer
+		Expect(png.Encode(&buf, huge)).To(Succeed())
+		_, err = images.Decode(buf.Bytes())
+		Expect(err).To(MatchError(images.ErrTooLarge))
+	})
+
+	It("should resize the image to the variants", func() {
+		resized, err := images.Process(encodePNG(1600, 900, color.NRGBA{R: 200, A: 255}))
+		Expect(err).NotTo(HaveOccurred())
+
+		sizes := map[string][2]int{}
+		for _, r := range resized {
+			sizes[r.Name] = [2]int{r.Width, r.Height}
+			img, err := jpeg.Decode(bytes.NewReader(r.Data))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(img.Bounds().Dx()).To(Equal(r.Width))
+			Expect(img.Bounds().Dy()).To(Equal(r.Height))
+		}
+		Expect(sizes).To(Equal(map[string][2]int{
+			"thumbnail": {150, 150},
+			"card":      {400, 300},
+			"hero":      {1200, 675},
+		}))
+	})
+
+	It("should not upscale the small images", func() {
+		img := image.NewRGBA(image.Rect(0, 0, 100, 40))
+		Expect(images.Resize(img, images.Variant{Width: 150, Height: 150, Crop: true}).Bounds().Size()).
+			To(Equal(image.Pt(40, 40)))
+		Expect(images.Resize(img, images.Variant{Width: 1200, Height: 800}).Bounds().Size()).
+			To(Equal(image.Pt(100, 40)))
+		Expect(images.Resize(img, images.Variant{Width: 50, Height: 50}).Bounds().Size()).
+			To(Equal(image.Pt(50, 20)))
+	})
+
+	It("should put the transparent images on the white background", func() {
+		resized := images.Resize(image.NewNRGBA(image.Rect(0, 0, 10, 10)), images.Variant{Width: 10, Height: 10})
+		r, g, b, _ := resized.At(5, 5).RGBA()
+		Expect([]uint32{r, g, b}).To(Equal([]uint32{0xffff, 0xffff, 0xffff}))
+	})
+})
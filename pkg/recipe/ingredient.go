@@ -0,0 +1,78 @@
+package recipe
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the ingredients
+const (
+	MaxIngredients        = 100
+	MaxIngredientNameLen  = 100
+	MaxIngredientNotesLen = 500
+	MaxIngredientGroupLen = 100
+	MaxIngredientUnitLen  = 20
+)
+
+// Ingredient is an entry of the recipe ingredients list. The quantity is
+// zero if it is not measured, e.g. salt to taste. The ingredients of the
+// same group, such as "For the sauce", are listed together
+type Ingredient struct {
+	Name     string  `json:"name" bson:"name"`
+	Quantity float64 `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	Unit     string  `json:"unit,omitempty" bson:"unit,omitempty"`
+	Notes    string  `json:"notes,omitempty" bson:"notes,omitempty"`
+	Group    string  `json:"group,omitempty" bson:"group,omitempty"`
+}
+
+// Validate checks the ingredient
+func (i *Ingredient) Validate() error {
+	switch {
+	case strings.TrimSpace(i.Name) == "":
+		return fmt.Errorf("name is required")
+	case utf8.RuneCountInString(i.Name) > MaxIngredientNameLen:
+		return fmt.Errorf("name is longer than %d", MaxIngredientNameLen)
+	case math.IsNaN(i.Quantity) || math.IsInf(i.Quantity, 0) || i.Quantity < 0:
+		return fmt.Errorf("quantity must be a non-negative number")
+	case i.Unit != "" && i.Quantity == 0:
+		return fmt.Errorf("unit %q is given without quantity", i.Unit)
+	case utf8.RuneCountInString(i.Unit) > MaxIngredientUnitLen:
+		return fmt.Errorf("unit is longer than %d", MaxIngredientUnitLen)
+	case utf8.RuneCountInString(i.Notes) > MaxIngredientNotesLen:
+		return fmt.Errorf("notes are longer than %d", MaxIngredientNotesLen)
+	case utf8.RuneCountInString(i.Group) > MaxIngredientGroupLen:
+		return fmt.Errorf("group is longer than %d", MaxIngredientGroupLen)
+	}
+	return nil
+}
+
+// IngredientKey returns the lower-case singular words of the ingredient
+// name, the ingredient tables are keyed by it
+func IngredientKey(name string) string {
+	words := strings.Fields(strings.ToLower(name))
+	for i, w := range words {
+		switch {
+		case strings.HasSuffix(w, "oes"):
+			words[i] = strings.TrimSuffix(w, "es")
+		case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && len(w) > 3:
+			words[i] = strings.TrimSuffix(w, "s")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// MatchIngredient returns the longest key contained in the ingredient name
+// as whole words, e.g. "2 skinless chicken breasts" is chicken breast
+// rather than chicken. The plurals match the singulars
+func MatchIngredient(name string, keys map[string]bool) (string, bool) {
+	padded := " " + IngredientKey(name) + " "
+	var best string
+	for key := range keys {
+		if len(key) > len(best) && strings.Contains(padded, " "+key+" ") {
+			best = key
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,140 @@
+package recipe
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SortField is the field the recipes can be sorted by
+type SortField string
+
+// Fields to sort the recipes by
+const (
+	SortByName          SortField = "name"
+	SortByAverageRating SortField = "averageRating"
+	SortByPrepTime      SortField = "prepTime"
+	SortByCookTime      SortField = "cookTime"
+	SortByTotalTime     SortField = "totalTime"
+)
+
+// Sort is the order of the recipes. The recipes with the equal values of
+// the field are ordered by ID in the same direction, so the order is stable
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+// ParseSort parses the sort order such as "name" or "-averageRating"
+func ParseSort(s string) (Sort, error) {
+	sort := Sort{Field: SortField(strings.TrimPrefix(s, "-")), Desc: strings.HasPrefix(s, "-")}
+	switch sort.Field {
+	case SortByName, SortByAverageRating, SortByPrepTime, SortByCookTime, SortByTotalTime:
+		return sort, nil
+	}
+	return Sort{}, fmt.Errorf("%w: unknown sort field %q", ErrValidation, sort.Field)
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + string(s.Field)
+	}
+	return string(s.Field)
+}
+
+// SortValue returns the value of the field the recipe is sorted by
+func (r *Recipe) SortValue(field SortField) interface{} {
+	switch field {
+	case SortByAverageRating:
+		return r.AverageRating
+	case SortByPrepTime:
+		return r.PrepTime.Seconds()
+	case SortByCookTime:
+		return r.CookTime.Seconds()
+	case SortByTotalTime:
+		return (r.PrepTime + r.CookTime).Seconds()
+	}
+	return r.Name
+}
+
+// Cursor is the position in the sorted recipes. The page starts right
+// after the position, or ends right before it if the cursor is Backward
+type Cursor struct {
+	Sort     string      `json:"s"`
+	Value    interface{} `json:"v"`
+	ID       string      `json:"id"`
+	Backward bool        `json:"b,omitempty"`
+}
+
+// NewCursor returns the cursor at the position of the recipe
+func NewCursor(r *Recipe, sort Sort, backward bool) *Cursor {
+	return &Cursor{
+		Sort:     sort.String(),
+		Value:    r.SortValue(sort.Field),
+		ID:       r.IDHex(),
+		Backward: backward,
+	}
+}
+
+// Encode returns the opaque string form of the cursor
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes the cursor and checks that it belongs to the sort order
+func DecodeCursor(s string, sort Sort) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrValidation)
+	}
+	c := &Cursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrValidation)
+	}
+	if c.Sort != sort.String() {
+		return nil, fmt.Errorf("%w: cursor belongs to the sort order %q", ErrValidation, c.Sort)
+	}
+
+	// JSON turns all the numbers to float64, keep the sort values typed
+	switch sort.Field {
+	case SortByName:
+		if _, ok := c.Value.(string); !ok {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrValidation)
+		}
+	case SortByAverageRating:
+		if _, ok := c.Value.(float64); !ok {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrValidation)
+		}
+	case SortByPrepTime, SortByCookTime, SortByTotalTime:
+		v, ok := c.Value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrValidation)
+		}
+		c.Value = int64(v)
+	}
+	return c, nil
+}
+
+// RangeQuery is the query of the recipes page. The storage returns up to
+// Limit recipes passing the filter next to the cursor in the direction of
+// the cursor, or from the beginning if there is no cursor. The recipes are
+// always in the sort order
+type RangeQuery struct {
+	Sort   Sort
+	Filter *Filter
+	Cursor *Cursor
+	Limit  int
+}
+
+// Page is the page of the recipes with the cursors to the adjacent pages
+type Page struct {
+	Recipes []*Recipe
+	Next    *Cursor
+	Prev    *Cursor
+	// Total is the number of the filtered recipes, if it was requested
+	Total *int64
+	// Facets of the filtered recipes, if they were requested
+	Facets *Facets
+}
@@ -0,0 +1,75 @@
+package recipe
+
+import "math"
+
+// Nutrients are the nutrition facts. The calories are in kcal, the sodium
+// in milligrams and the rest in grams
+type Nutrients struct {
+	Calories      float64 `json:"calories" bson:"calories"`
+	Protein       float64 `json:"protein" bson:"protein"`
+	Fat           float64 `json:"fat" bson:"fat"`
+	Carbohydrates float64 `json:"carbohydrates" bson:"carbohydrates"`
+	Fiber         float64 `json:"fiber" bson:"fiber"`
+	Sodium        float64 `json:"sodium" bson:"sodium"`
+}
+
+// Add adds the nutrients multiplied by the factor
+func (n *Nutrients) Add(o Nutrients, factor float64) {
+	n.Calories += o.Calories * factor
+	n.Protein += o.Protein * factor
+	n.Fat += o.Fat * factor
+	n.Carbohydrates += o.Carbohydrates * factor
+	n.Fiber += o.Fiber * factor
+	n.Sodium += o.Sodium * factor
+}
+
+// Scaled returns the nutrients multiplied by the factor and rounded to
+// one decimal
+func (n Nutrients) Scaled(factor float64) Nutrients {
+	round := func(v float64) float64 {
+		return math.Round(v*factor*10) / 10
+	}
+	return Nutrients{
+		Calories:      round(n.Calories),
+		Protein:       round(n.Protein),
+		Fat:           round(n.Fat),
+		Carbohydrates: round(n.Carbohydrates),
+		Fiber:         round(n.Fiber),
+		Sodium:        round(n.Sodium),
+	}
+}
+
+// Nutrition is the nutrition of the recipe computed from its ingredients.
+// The storage keeps it with the recipe and recomputes it on every change
+type Nutrition struct {
+	Total Nutrients `json:"total" bson:"total"`
+	// PerServing is given if the servings of the recipe are known
+	PerServing *Nutrients `json:"perServing,omitempty" bson:"perServing,omitempty"`
+	// Missing are the names of the measured ingredients which could not be
+	// matched, their nutrition is not counted in
+	Missing []string `json:"missing,omitempty" bson:"missing,omitempty"`
+}
+
+// NewNutrition returns the nutrition of the total nutrients for the servings
+func NewNutrition(total Nutrients, servings int, missing []string) *Nutrition {
+	n := &Nutrition{Total: total.Scaled(1), Missing: missing}
+	if servings > 0 {
+		perServing := total.Scaled(1 / float64(servings))
+		n.PerServing = &perServing
+	}
+	return n
+}
+
+// Copy returns the deep copy of the nutrition, nil stays nil
+func (n *Nutrition) Copy() *Nutrition {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	if n.PerServing != nil {
+		perServing := *n.PerServing
+		c.PerServing = &perServing
+	}
+	c.Missing = append([]string(nil), n.Missing...)
+	return &c
+}
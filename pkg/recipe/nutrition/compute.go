@@ -0,0 +1,57 @@
+package nutrition
+
+import (
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/units"
+)
+
+// Compute returns the nutrition of the recipe computed from the quantities
+// of its ingredients. The ingredients which are not in the table, or which
+// quantity cannot be weighed, e.g. a volume without the density, are
+// reported as missing. The unmeasured ones, such as salt to taste, are not
+// counted
+func (t *Table) Compute(r *recipe.Recipe) *recipe.Nutrition {
+	var total recipe.Nutrients
+	var missing []string
+	for _, ingredient := range r.Ingredients {
+		if ingredient.Quantity == 0 {
+			continue
+		}
+		e, ok := t.Lookup(ingredient.Name)
+		if !ok {
+			missing = append(missing, ingredient.Name)
+			continue
+		}
+		grams, ok := e.grams(ingredient)
+		if !ok {
+			missing = append(missing, ingredient.Name)
+			continue
+		}
+		total.Add(e.Nutrients, grams/100)
+	}
+	return recipe.NewNutrition(total, r.Servings, missing)
+}
+
+// grams returns the weight of the ingredient quantity
+func (e *Entry) grams(ingredient recipe.Ingredient) (float64, bool) {
+	u := units.Piece
+	if ingredient.Unit != "" {
+		var err error
+		if u, err = units.Lookup(ingredient.Unit); err != nil {
+			return 0, false
+		}
+	}
+
+	switch u.Dimension {
+	case units.Count:
+		return ingredient.Quantity * e.PieceGrams, e.PieceGrams > 0
+	case units.Mass, units.Volume:
+		density := e.Density
+		if density == 0 {
+			density, _ = units.Density(e.Name)
+		}
+		grams, err := units.Convert(ingredient.Quantity, u, units.Gram, density)
+		return grams, err == nil
+	}
+	return 0, false
+}
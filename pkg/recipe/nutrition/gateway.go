@@ -0,0 +1,30 @@
+package nutrition
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+type nutritionGateway struct {
+	recipe.StorageGateway
+	table *Table
+}
+
+// NewGateway create a storage gateway which computes the nutrition of the
+// recipes by the table and stores it with them, so it is recomputed
+// whenever the recipe changes. The recipes stored before get the nutrition
+// on their next update
+func NewGateway(s recipe.StorageGateway, table *Table) recipe.StorageGateway {
+	return &nutritionGateway{StorageGateway: s, table: table}
+}
+
+func (s *nutritionGateway) Store(ctx context.Context, r *recipe.Recipe) error {
+	r.Nutrition = s.table.Compute(r)
+	return s.StorageGateway.Store(ctx, r)
+}
+
+func (s *nutritionGateway) Update(ctx context.Context, r *recipe.Recipe) error {
+	r.Nutrition = s.table.Compute(r)
+	return s.StorageGateway.Update(ctx, r)
+}
@@ -0,0 +1,118 @@
+package nutrition_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/nutrition"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const table = `# per 100 g
+name,calories,protein,fat,carbohydrates,fiber,sodium,pieceGrams,density
+egg,143,12.6,9.5,0.7,0,142,50,
+flour,364,10,1,76,2.7,2,,0.5
+milk,60,3,3,5,0,40,,
+chicken,240,27,14,0,0,80,,
+chicken breast,165,31,3.6,0,0,74,,
+tomato,18,0.9,0.2,3.9,1.2,5,,
+`
+
+var _ = Describe("Nutrition", func() {
+	var t *nutrition.Table
+
+	BeforeEach(func() {
+		var err error
+		t, err = nutrition.ReadCSV(strings.NewReader(table))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should read the tables", func() {
+		Expect(t.Len()).To(Equal(6))
+
+		fromJSON, err := nutrition.ReadJSON(strings.NewReader(`[{"name": "egg", "calories": 143, "pieceGrams": 50}]`))
+		Expect(err).NotTo(HaveOccurred())
+		e, ok := fromJSON.Lookup("egg")
+		Expect(ok).To(BeTrue())
+		Expect(e.Calories).To(Equal(143.0))
+		Expect(e.PieceGrams).To(Equal(50.0))
+
+		_, err = nutrition.ReadCSV(strings.NewReader("name,calories\negg,143\n"))
+		Expect(err).To(HaveOccurred())
+		_, err = nutrition.ReadCSV(strings.NewReader(table + "rice,lots,7,0.7,80,1.3,5,,\n"))
+		Expect(err).To(MatchError(ContainSubstring("invalid calories")))
+		_, err = nutrition.ReadCSV(strings.NewReader(table + "Eggs,143,12.6,9.5,0.7,0,142,50,\n"))
+		Expect(err).To(MatchError(ContainSubstring("duplicate")))
+	})
+
+	It("should load the table of the configuration", func() {
+		loaded, err := nutrition.Load("../../../configs/nutrition.csv")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Len()).To(BeNumerically(">", 10))
+	})
+
+	It("should match the longest name and the plurals", func() {
+		e, _ := t.Lookup("2 skinless Chicken Breasts")
+		Expect(e.Name).To(Equal("chicken breast"))
+		e, _ = t.Lookup("chicken thighs")
+		Expect(e.Name).To(Equal("chicken"))
+		e, _ = t.Lookup("ripe tomatoes")
+		Expect(e.Name).To(Equal("tomato"))
+
+		_, ok := t.Lookup("eggplant")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should compute the nutrition of the recipe", func() {
+		n := t.Compute(&recipe.Recipe{
+			Servings: 2,
+			Ingredients: []recipe.Ingredient{
+				{Name: "eggs", Quantity: 2},
+				{Name: "flour", Quantity: 0.2, Unit: "kg"},
+				{Name: "flour", Quantity: 10, Unit: "ml"},
+				{Name: "salt", Notes: "to taste"},
+			},
+		})
+		// 100 g of eggs, 200 g and 5 g of flour
+		Expect(n.Total.Calories).To(Equal(143 + 3.64*205))
+		Expect(n.Total.Protein).To(Equal(12.6 + 20.5))
+		Expect(n.PerServing.Calories).To(Equal(444.6))
+		Expect(n.Missing).To(BeEmpty())
+	})
+
+	It("should report the ingredients it cannot count", func() {
+		n := t.Compute(&recipe.Recipe{
+			Ingredients: []recipe.Ingredient{
+				{Name: "milk", Quantity: 200, Unit: "g"},
+				{Name: "milk", Quantity: 1, Unit: "cup"},
+				{Name: "tomato", Quantity: 2},
+				{Name: "saffron", Quantity: 1, Unit: "pinch"},
+				{Name: "eggplant", Quantity: 300, Unit: "g"},
+			},
+		})
+		// milk has no density in the table, but its common density is known
+		Expect(n.Total.Calories).To(BeNumerically("~", 120+0.6*236.6*1.03, 0.1))
+		Expect(n.PerServing).To(BeNil())
+		Expect(n.Missing).To(Equal([]string{"tomato", "saffron", "eggplant"}))
+	})
+
+	It("should store the nutrition and recompute it on update", func() {
+		ctx := context.Background()
+		storage := nutrition.NewGateway(gateways.NewMemoryGateway(), t)
+		r := &recipe.Recipe{Name: "Omelette", Servings: 1, Ingredients: []recipe.Ingredient{{Name: "egg", Quantity: 2}}}
+		Expect(storage.Store(ctx, r)).To(Succeed())
+
+		stored, err := storage.GetByID(ctx, r.IDHex())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Nutrition.Total.Calories).To(Equal(143.0))
+
+		stored.Ingredients[0].Quantity = 3
+		Expect(storage.Update(ctx, stored)).To(Succeed())
+		stored, err = storage.GetByID(ctx, r.IDHex())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Nutrition.Total.Calories).To(Equal(214.5))
+	})
+})
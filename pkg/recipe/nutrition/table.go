@@ -0,0 +1,138 @@
+package nutrition
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// Entry is the nutrition of the ingredient per 100 g. The weight of a
+// piece and the density in grams per milliliter are needed to count the
+// ingredients measured in pieces and by volume
+type Entry struct {
+	Name string `json:"name"`
+	recipe.Nutrients
+	PieceGrams float64 `json:"pieceGrams,omitempty"`
+	Density    float64 `json:"density,omitempty"`
+}
+
+// Table is the nutrition table of the ingredients
+type Table struct {
+	entries map[string]*Entry
+	keys    map[string]bool
+}
+
+// NewTable returns the table of the entries
+func NewTable(entries []*Entry) (*Table, error) {
+	t := &Table{entries: make(map[string]*Entry, len(entries)), keys: make(map[string]bool, len(entries))}
+	for i, e := range entries {
+		key := recipe.IngredientKey(e.Name)
+		switch {
+		case key == "":
+			return nil, fmt.Errorf("entry %d: name is required", i+1)
+		case t.entries[key] != nil:
+			return nil, fmt.Errorf("entry %d: duplicate name %q", i+1, e.Name)
+		}
+		t.entries[key] = e
+		t.keys[key] = true
+	}
+	return t, nil
+}
+
+// Len returns the number of the entries
+func (t *Table) Len() int {
+	return len(t.entries)
+}
+
+// Lookup returns the entry of the ingredient, see recipe.MatchIngredient
+func (t *Table) Lookup(ingredient string) (*Entry, bool) {
+	key, ok := recipe.MatchIngredient(ingredient, t.keys)
+	if !ok {
+		return nil, false
+	}
+	return t.entries[key], true
+}
+
+// Load reads the table from the CSV or JSON file, by its extension
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReadCSV(f)
+	case ".json":
+		return ReadJSON(f)
+	}
+	return nil, fmt.Errorf("unknown format of the nutrition table %s", path)
+}
+
+// ReadJSON reads the table from the JSON array of the entries
+func ReadJSON(r io.Reader) (*Table, error) {
+	var entries []*Entry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return NewTable(entries)
+}
+
+// csvColumns are the columns of the CSV table, the header names them in
+// any order. The pieceGrams and density columns are optional
+var csvColumns = []string{"name", "calories", "protein", "fat", "carbohydrates", "fiber", "sodium", "pieceGrams", "density"}
+
+// ReadCSV reads the table from CSV with the header
+func ReadCSV(r io.Reader) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	for _, name := range csvColumns[:7] {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("column %s is missing", name)
+		}
+	}
+
+	var entries []*Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		e := &Entry{Name: record[index["name"]]}
+		values := []*float64{
+			&e.Calories, &e.Protein, &e.Fat, &e.Carbohydrates, &e.Fiber, &e.Sodium, &e.PieceGrams, &e.Density,
+		}
+		for i, column := range csvColumns[1:] {
+			j, ok := index[column]
+			if !ok || strings.TrimSpace(record[j]) == "" {
+				continue
+			}
+			if *values[i], err = strconv.ParseFloat(strings.TrimSpace(record[j]), 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, column, record[j])
+			}
+		}
+		entries = append(entries, e)
+	}
+	return NewTable(entries)
+}
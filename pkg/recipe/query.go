@@ -0,0 +1,468 @@
+package recipe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is the parsed search query. It is made of And, Or, Not, Text and
+// Compare. The storages which cannot translate the expression use Match
+type Expr interface {
+	// Match tells whether the recipe passes the expression
+	Match(r *Recipe) bool
+}
+
+// And matches the recipes passing all the expressions
+type And []Expr
+
+// Or matches the recipes passing any of the expressions
+type Or []Expr
+
+// Not matches the recipes not passing the expression
+type Not struct {
+	Expr Expr
+}
+
+// Text matches the recipes which names contain the text, case-insensitive
+type Text string
+
+// QueryField is the recipe field the query compares
+type QueryField string
+
+// Fields of the query
+const (
+	FieldDifficulty   QueryField = "difficulty"
+	FieldVegetarian   QueryField = "vegetarian"
+	FieldRating       QueryField = "rating"
+	FieldRatingsCount QueryField = "ratings"
+	FieldPrepTime     QueryField = "prep"
+	FieldCookTime     QueryField = "cook"
+	FieldTotalTime    QueryField = "total"
+)
+
+// CompareOp is the comparison of the field with the value
+type CompareOp string
+
+// Comparisons of the query
+const (
+	OpEq CompareOp = "="
+	OpLt CompareOp = "<"
+	OpLe CompareOp = "<="
+	OpGt CompareOp = ">"
+	OpGe CompareOp = ">="
+)
+
+// Compare matches the recipes which field compares with the value. The
+// value is a Difficulty, bool, float64 (rating), int64 (ratings count) or
+// time.Duration (preparation, cooking or total time)
+type Compare struct {
+	Field QueryField
+	Op    CompareOp
+	Value interface{}
+}
+
+// Match implements Expr
+func (e And) Match(r *Recipe) bool {
+	for _, expr := range e {
+		if !expr.Match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match implements Expr
+func (e Or) Match(r *Recipe) bool {
+	for _, expr := range e {
+		if expr.Match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match implements Expr
+func (e Not) Match(r *Recipe) bool {
+	return !e.Expr.Match(r)
+}
+
+// Match implements Expr
+func (e Text) Match(r *Recipe) bool {
+	return strings.Contains(strings.ToLower(r.Name), strings.ToLower(string(e)))
+}
+
+// Match implements Expr
+func (e Compare) Match(r *Recipe) bool {
+	var c int
+	switch v := e.Value.(type) {
+	case Difficulty:
+		c = compareNumbers(int64(r.Difficulty), int64(v))
+	case bool:
+		return r.Vegetarian == v
+	case float64:
+		c = compareNumbers(r.AverageRating, v)
+	case int64:
+		c = compareNumbers(r.RatingsCount, v)
+	case time.Duration:
+		d := r.PrepTime
+		switch e.Field {
+		case FieldCookTime:
+			d = r.CookTime
+		case FieldTotalTime:
+			d = r.PrepTime + r.CookTime
+		}
+		c = compareNumbers(d.Seconds(), int64(v/time.Second))
+	default:
+		return false
+	}
+
+	switch e.Op {
+	case OpLt:
+		return c < 0
+	case OpLe:
+		return c <= 0
+	case OpGt:
+		return c > 0
+	case OpGe:
+		return c >= 0
+	}
+	return c == 0
+}
+
+func compareNumbers(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		b := b.(int64)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	case float64:
+		b := b.(float64)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	}
+	return 0
+}
+
+// QueryError is the syntax error of the search query
+type QueryError struct {
+	// Pos is the offset of the error in the query, in bytes from 0
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%v: query error at %d: %s", ErrValidation, e.Pos, e.Msg)
+}
+
+// Unwrap makes the query errors validation errors
+func (e *QueryError) Unwrap() error {
+	return ErrValidation
+}
+
+// queryAliases are the names of the fields the query understands
+var queryAliases = map[string]QueryField{
+	"difficulty":    FieldDifficulty,
+	"vegetarian":    FieldVegetarian,
+	"rating":        FieldRating,
+	"averagerating": FieldRating,
+	"ratings":       FieldRatingsCount,
+	"ratingscount":  FieldRatingsCount,
+	"prep":          FieldPrepTime,
+	"preptime":      FieldPrepTime,
+	"cook":          FieldCookTime,
+	"cooktime":      FieldCookTime,
+	"total":         FieldTotalTime,
+	"totaltime":     FieldTotalTime,
+}
+
+// ParseQuery parses the search query such as
+//
+//	chicken difficulty:easy vegetarian:false rating>=4 prep<30m
+//
+// The words and "quoted phrases" match the names. The fields are compared
+// by ":" or "=", "<", "<=", ">", ">=": difficulty (easy, normal, hard or
+// 1-3), vegetarian (true or false), rating, ratings (the count), prep, cook
+// and total (Go or ISO-8601 durations) and name (the same as the plain words). The terms
+// are joined by AND unless OR is given, OR binds weaker. NOT or "-" negate
+// the term and the parentheses group them
+func ParseQuery(s string) (Expr, error) {
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &QueryError{Pos: tok.pos, Msg: "unexpected closing parenthesis"}
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+	pos  int
+	// quoted words are taken as they are
+	quoted bool
+}
+
+// keyword tells whether the token is the unquoted keyword
+func (t queryToken) keyword(kw string) bool {
+	return t.kind == tokenWord && !t.quoted && t.text == kw
+}
+
+// lexQuery splits the query into the words and parentheses. The quoted
+// parts of the words keep the spaces and the parentheses
+func lexQuery(s string) ([]queryToken, error) {
+	var tokens []queryToken
+	i := 0
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen, pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose, pos: i})
+			i++
+		default:
+			tok := queryToken{kind: tokenWord, pos: i, quoted: c == '"'}
+			var b strings.Builder
+			for i < len(s) && !strings.ContainsRune(" \t\n\r()", rune(s[i])) {
+				if s[i] == '"' {
+					end := strings.IndexByte(s[i+1:], '"')
+					if end < 0 {
+						return nil, &QueryError{Pos: i, Msg: "unterminated quote"}
+					}
+					b.WriteString(s[i+1 : i+1+end])
+					i += end + 2
+					continue
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			tok.text = b.String()
+			tokens = append(tokens, tok)
+		}
+	}
+	return append(tokens, queryToken{kind: tokenEOF, pos: len(s)}), nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	next   int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) take() queryToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+// parseOr parses the terms joined by OR
+func (p *queryParser) parseOr() (Expr, error) {
+	var or Or
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, expr)
+		if !p.peek().keyword("OR") {
+			break
+		}
+		p.take()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+// parseAnd parses the terms joined by AND or nothing
+func (p *queryParser) parseAnd() (Expr, error) {
+	var and And
+	for {
+		tok := p.peek()
+		if tok.kind == tokenEOF || tok.kind == tokenClose || tok.keyword("OR") {
+			break
+		}
+		if tok.keyword("AND") {
+			if len(and) == 0 {
+				return nil, &QueryError{Pos: tok.pos, Msg: "AND without the left term"}
+			}
+			p.take()
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+	}
+
+	switch len(and) {
+	case 0:
+		tok := p.peek()
+		return nil, &QueryError{Pos: tok.pos, Msg: "expected a term"}
+	case 1:
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// parseUnary parses the term with the optional negation
+func (p *queryParser) parseUnary() (Expr, error) {
+	tok := p.peek()
+	switch {
+	case tok.keyword("NOT"), tok.keyword("-"):
+		p.take()
+		if next := p.peek(); next.kind == tokenEOF || next.kind == tokenClose || next.keyword("OR") {
+			return nil, &QueryError{Pos: next.pos, Msg: "expected a term to negate"}
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{expr}, nil
+
+	case tok.kind == tokenWord && !tok.quoted && strings.HasPrefix(tok.text, "-"):
+		p.take()
+		expr, err := parseTerm(tok.text[1:], tok.pos+1)
+		if err != nil {
+			return nil, err
+		}
+		return Not{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses the group or the term
+func (p *queryParser) parsePrimary() (Expr, error) {
+	tok := p.take()
+	switch tok.kind {
+	case tokenOpen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.take().kind != tokenClose {
+			return nil, &QueryError{Pos: tok.pos, Msg: "missing closing parenthesis"}
+		}
+		return expr, nil
+	case tokenWord:
+		if tok.quoted {
+			return Text(tok.text), nil
+		}
+		return parseTerm(tok.text, tok.pos)
+	}
+	return nil, &QueryError{Pos: tok.pos, Msg: "expected a term"}
+}
+
+// parseTerm parses the word or the comparison of the field
+func parseTerm(s string, pos int) (Expr, error) {
+	i := strings.IndexAny(s, ":=<>")
+	if i < 0 {
+		return Text(s), nil
+	}
+	name := s[:i]
+	if name == "" {
+		return nil, &QueryError{Pos: pos, Msg: "missing field name"}
+	}
+
+	op := OpEq
+	rest := s[i:]
+	for _, candidate := range []CompareOp{OpLe, OpGe, OpLt, OpGt, OpEq, ":"} {
+		if strings.HasPrefix(rest, string(candidate)) {
+			if candidate != ":" {
+				op = candidate
+			}
+			rest = rest[len(candidate):]
+			break
+		}
+	}
+	valuePos := pos + len(s) - len(rest)
+	if rest == "" {
+		return nil, &QueryError{Pos: valuePos, Msg: fmt.Sprintf("missing value of %s", name)}
+	}
+
+	if strings.ToLower(name) == "name" {
+		if op != OpEq {
+			return nil, &QueryError{Pos: pos + i, Msg: "name can only be matched by ':'"}
+		}
+		return Text(rest), nil
+	}
+	field, ok := queryAliases[strings.ToLower(name)]
+	if !ok {
+		return nil, &QueryError{Pos: pos, Msg: fmt.Sprintf("unknown field %q", name)}
+	}
+
+	invalid := func(what string) error {
+		return &QueryError{Pos: valuePos, Msg: fmt.Sprintf("%s must be %s", name, what)}
+	}
+	cmp := Compare{Field: field, Op: op}
+	switch field {
+	case FieldDifficulty:
+		d, err := ParseDifficulty(rest)
+		if err != nil {
+			return nil, invalid("easy, normal, hard or 1-3")
+		}
+		cmp.Value = d
+	case FieldVegetarian:
+		v, err := strconv.ParseBool(rest)
+		if err != nil {
+			return nil, invalid("true or false")
+		}
+		if op != OpEq {
+			return nil, &QueryError{Pos: pos + i, Msg: "vegetarian can only be matched by ':'"}
+		}
+		cmp.Value = v
+	case FieldRating:
+		v, err := strconv.ParseFloat(rest, 64)
+		if err != nil {
+			return nil, invalid("a number")
+		}
+		cmp.Value = v
+	case FieldRatingsCount:
+		v, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil {
+			return nil, invalid("an integer")
+		}
+		cmp.Value = v
+	case FieldPrepTime, FieldCookTime, FieldTotalTime:
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			if d, err = ParseISODuration(strings.ToUpper(rest)); err != nil {
+				return nil, invalid("a duration such as 30m or PT30M")
+			}
+		}
+		cmp.Value = d
+	}
+	return cmp, nil
+}
@@ -1,5 +1,20 @@
 package recipe
 
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the recipe
+const (
+	MaxNameLen = 200
+	// MaxRating is the highest score of the recipe
+	MaxRating = 5
+)
+
 // Difficulty is a type to represent difficulty levels
 type Difficulty int
 
@@ -10,13 +25,142 @@ const (
 	Hard
 )
 
+// ParseDifficulty parses the difficulty level given by its name or number
+func ParseDifficulty(s string) (Difficulty, error) {
+	switch strings.ToLower(s) {
+	case "easy":
+		return Easy, nil
+	case "normal":
+		return Normal, nil
+	case "hard":
+		return Hard, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || Difficulty(n) < Easy || Difficulty(n) > Hard {
+		return 0, fmt.Errorf("%w: unknown difficulty %q", ErrValidation, s)
+	}
+	return Difficulty(n), nil
+}
+
 // Recipe is a recipe entry
 type Recipe struct {
 	ID            interface{} `json:"_id,omitempty" bson:"_id,omitempty"`
 	Name          string      `json:"name" bson:"name"`
-	PrepTime      string      `json:"prepTime" bson:"prepTime"`
+	PrepTime      Duration    `json:"prepTime" bson:"-"`
+	CookTime      Duration    `json:"cookTime,omitempty" bson:"-"`
 	Difficulty    Difficulty  `json:"difficulty" bson:"difficulty"`
 	Vegetarian    bool        `json:"vegetarian" bson:"vegetarian"`
 	AverageRating float64     `json:"averageRating" bson:"averageRating"`
 	RatingsCount  int64       `json:"ratingsCount" bson:"ratingsCount"`
+	// RatingsSum is the sum of the scores, the average rating is derived
+	// from it, so it stays exact however many ratings there are
+	RatingsSum  float64      `json:"-" bson:"ratingsSum"`
+	Servings    int          `json:"servings,omitempty" bson:"servings,omitempty"`
+	Ingredients []Ingredient `json:"ingredients,omitempty" bson:"ingredients,omitempty"`
+	Steps       []Step       `json:"steps,omitempty" bson:"steps,omitempty"`
+	Nutrition   *Nutrition   `json:"nutrition,omitempty" bson:"nutrition,omitempty"`
+	// Diets and Allergens are set by Classify, the allergens given by the
+	// client are ignored, they are corrected by the overrides instead
+	Diets             []Diet             `json:"diets,omitempty" bson:"diets,omitempty"`
+	Allergens         []Allergen         `json:"allergens,omitempty" bson:"allergens,omitempty"`
+	AllergenOverrides *AllergenOverrides `json:"allergenOverrides,omitempty" bson:"allergenOverrides,omitempty"`
+	// Cuisines, Meals and Tags are the slugs of the taxonomy terms
+	Cuisines []string `json:"cuisines,omitempty" bson:"cuisines,omitempty"`
+	Meals    []string `json:"meals,omitempty" bson:"meals,omitempty"`
+	Tags     []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	// Images are changed by AddImage and RemoveImage of the storage only
+	Images []Image `json:"images,omitempty" bson:"images,omitempty"`
+	// TotalTime is computed by ComputeTotalTime, the given value is ignored.
+	// The storages keep the recipe durations as ISO-8601 strings
+	TotalTime Duration `json:"totalTime" bson:"-"`
+	// Version is incremented by the storage on every change of the recipe.
+	// The update of the recipe with non-zero version succeeds only if the
+	// stored version is the same
+	Version int64 `json:"version" bson:"version"`
+}
+
+// IDHex returns the recipe ID as a hex string. It handles both string IDs
+// and the native ObjectIds of the MongoDB drivers
+func (r *Recipe) IDHex() string {
+	switch v := r.ID.(type) {
+	case string:
+		return v
+	case interface{ Hex() string }:
+		return v.Hex()
+	}
+	return ""
+}
+
+// Validate checks the recipe before it is stored. It returns the
+// ValidationError with the errors of all the invalid fields
+func (r *Recipe) Validate() error {
+	v := &ValidationError{}
+	switch name := strings.TrimSpace(r.Name); {
+	case name == "":
+		v.Add("name", "is required")
+	case utf8.RuneCountInString(name) > MaxNameLen:
+		v.Add("name", "is longer than %d", MaxNameLen)
+	}
+	if r.Difficulty < Easy || r.Difficulty > Hard {
+		v.Add("difficulty", "must be from %d to %d", Easy, Hard)
+	}
+	if r.PrepTime < 0 {
+		v.Add("prepTime", "cannot be negative")
+	}
+	if r.CookTime < 0 {
+		v.Add("cookTime", "cannot be negative")
+	}
+	if r.Servings < 0 || r.Servings > MaxServings {
+		v.Add("servings", "must be from 0 to %d", MaxServings)
+	}
+	r.validateRatings(v)
+
+	if len(r.Ingredients) > MaxIngredients {
+		v.Add("ingredients", "more than %d ingredients", MaxIngredients)
+	}
+	for i := range r.Ingredients {
+		if err := r.Ingredients[i].Validate(); err != nil {
+			v.AddError(fmt.Sprintf("ingredients[%d]", i), err)
+		}
+	}
+	if len(r.Steps) > MaxSteps {
+		v.Add("steps", "more than %d steps", MaxSteps)
+	}
+	for i := range r.Steps {
+		if err := r.Steps[i].Validate(r.Ingredients); err != nil {
+			v.AddError(fmt.Sprintf("steps[%d]", i), err)
+		}
+	}
+	for i, d := range r.Diets {
+		if _, err := ParseDiet(string(d)); err != nil {
+			v.AddError(fmt.Sprintf("diets[%d]", i), err)
+		}
+	}
+	if err := r.AllergenOverrides.Validate(); err != nil {
+		v.AddError("allergenOverrides", err)
+	}
+	r.validateTerms(v)
+	return v.Err()
+}
+
+// validateRatings checks the ratings, they are given by the imports only
+func (r *Recipe) validateRatings(v *ValidationError) {
+	if r.RatingsCount < 0 {
+		v.Add("ratingsCount", "cannot be negative")
+	}
+	switch {
+	case math.IsNaN(r.AverageRating) || r.AverageRating < 0 || r.AverageRating > MaxRating:
+		v.Add("averageRating", "must be from 0 to %d", MaxRating)
+	case r.RatingsCount == 0 && r.AverageRating != 0:
+		v.Add("averageRating", "must be 0 without ratings")
+	case r.RatingsCount > 0 && r.AverageRating < 1:
+		v.Add("averageRating", "must be from 1 to %d with ratings", MaxRating)
+	}
+}
+
+// AddRating adds the score to the ratings of the recipe
+func (r *Recipe) AddRating(score uint8) {
+	r.RatingsCount++
+	r.RatingsSum += float64(score)
+	r.AverageRating = r.RatingsSum / float64(r.RatingsCount)
 }
@@ -0,0 +1,219 @@
+package recipe
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SearchMode is the way the search text matches the recipe names
+type SearchMode string
+
+// Search modes
+const (
+	// SearchText matches the names containing the text, case-insensitive
+	SearchText SearchMode = "text"
+	// SearchPrefix matches the names with a word starting with the text, case-insensitive
+	SearchPrefix SearchMode = "prefix"
+	// SearchFullText matches the names containing any word of the text,
+	// the relevance comes from the full-text index
+	SearchFullText SearchMode = "fulltext"
+	// SearchRegex matches the names by the raw regular expression. It is
+	// an explicit opt-in, since the expression is run by the storage as is
+	SearchRegex SearchMode = "regex"
+	// SearchQueryLanguage matches the recipes by the query, see ParseQuery.
+	// All the matches score 1
+	SearchQueryLanguage SearchMode = "query"
+)
+
+// Limits of the search query
+const (
+	MaxSearchLimit      = 100
+	MaxSearchTextLength = 256
+)
+
+// ParseSearchMode parses the search mode, the text mode is the default one
+func ParseSearchMode(s string) (SearchMode, error) {
+	switch mode := SearchMode(s); mode {
+	case "":
+		return SearchText, nil
+	case SearchText, SearchPrefix, SearchFullText, SearchRegex, SearchQueryLanguage:
+		return mode, nil
+	}
+	return "", fmt.Errorf("%w: unknown search mode %q", ErrValidation, s)
+}
+
+// SearchQuery is the query of the recipes search. The results are sorted
+// by the descending relevance, then by name and ID
+type SearchQuery struct {
+	Text string
+	Mode SearchMode
+	// Fuzziness is the number of the edits a term of the text and a word of
+	// the name may differ by, or FuzzyAuto. The text and fulltext modes only
+	Fuzziness int
+	Offset    int
+	Limit     int
+}
+
+// Validate checks the search query
+func (q *SearchQuery) Validate() error {
+	switch {
+	case strings.TrimSpace(q.Text) == "":
+		return fmt.Errorf("%w: no search text given", ErrValidation)
+	case len(q.Text) > MaxSearchTextLength:
+		return fmt.Errorf("%w: search text is longer than %d", ErrValidation, MaxSearchTextLength)
+	case q.Offset < 0:
+		return fmt.Errorf("%w: negative search offset", ErrValidation)
+	case q.Limit < 1 || q.Limit > MaxSearchLimit:
+		return fmt.Errorf("%w: search limit must be from 1 to %d", ErrValidation, MaxSearchLimit)
+	case q.Fuzziness < FuzzyAuto || q.Fuzziness > MaxFuzziness:
+		return fmt.Errorf("%w: fuzziness must be auto or from 0 to %d", ErrValidation, MaxFuzziness)
+	}
+	mode, err := ParseSearchMode(string(q.Mode))
+	if err != nil {
+		return err
+	}
+	if q.Fuzziness != 0 && mode != SearchText && mode != SearchFullText {
+		return fmt.Errorf("%w: %s search is not fuzzy", ErrValidation, mode)
+	}
+	if mode == SearchQueryLanguage {
+		if _, err := ParseQuery(q.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Suggestive tells whether the text is made of the words looked for in the
+// names, so the close words can be suggested when nothing is found
+func (q *SearchQuery) Suggestive() bool {
+	return q.Mode != SearchRegex && q.Mode != SearchQueryLanguage
+}
+
+// Expression returns the parsed text of the query language search
+func (q *SearchQuery) Expression() (Expr, error) {
+	return ParseQuery(q.Text)
+}
+
+// SearchHit is the found recipe with its relevance
+type SearchHit struct {
+	*Recipe
+	Score float64 `json:"score"`
+}
+
+// SearchResult is the page of the found recipes
+type SearchResult struct {
+	Hits []*SearchHit
+	// Total is the number of all the found recipes
+	Total int64
+	// Suggestions are the known words close to the text, if nothing is found
+	Suggestions []string
+}
+
+// SortHits sorts the hits by the descending score, then by name and ID
+func SortHits(hits []*SearchHit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.IDHex() < b.IDHex()
+	})
+}
+
+// SearchTerms splits the text into the lower-case words
+func SearchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Matcher returns the function scoring the recipe against the query.
+// It is the reference of the search semantics for the storages which
+// cannot run the search natively. The text and prefix modes score the
+// match by the share of the name it covers and how close it is to the
+// beginning, the regex matches score 1. The fuzzy text mode also matches
+// the names with the words close to all the terms, and the fuzzy fulltext
+// mode counts the close words. Such matches score by the similarity
+func (q *SearchQuery) Matcher() (func(r *Recipe) (float64, bool), error) {
+	text := strings.ToLower(q.Text)
+	switch q.Mode {
+	case SearchRegex:
+		regex, err := regexp.Compile(q.Text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+		return func(r *Recipe) (float64, bool) {
+			return 1, regex.MatchString(r.Name)
+		}, nil
+
+	case SearchQueryLanguage:
+		expr, err := q.Expression()
+		if err != nil {
+			return nil, err
+		}
+		return func(r *Recipe) (float64, bool) {
+			return 1, expr.Match(r)
+		}, nil
+
+	case SearchPrefix:
+		regex := regexp.MustCompile(`\b` + regexp.QuoteMeta(text))
+		return func(r *Recipe) (float64, bool) {
+			name := strings.ToLower(r.Name)
+			if !regex.MatchString(name) {
+				return 0, false
+			}
+			return substringScore(name, text), true
+		}, nil
+
+	case SearchFullText:
+		terms := SearchTerms(q.Text)
+		return func(r *Recipe) (float64, bool) {
+			words := SearchTerms(r.Name)
+			matched := 0.0
+			for _, term := range terms {
+				if sim, ok := FuzzyMatch(term, words, q.Fuzziness); ok {
+					matched += sim
+				}
+			}
+			if matched == 0 {
+				return 0, false
+			}
+			return matched / float64(len(words)), true
+		}, nil
+	}
+
+	terms := SearchTerms(q.Text)
+	return func(r *Recipe) (float64, bool) {
+		name := strings.ToLower(r.Name)
+		if strings.Contains(name, text) {
+			return substringScore(name, text), true
+		}
+		if q.Fuzziness == 0 || len(terms) == 0 {
+			return 0, false
+		}
+		words := SearchTerms(name)
+		matched := 0.0
+		for _, term := range terms {
+			sim, ok := FuzzyMatch(term, words, q.Fuzziness)
+			if !ok {
+				return 0, false
+			}
+			matched += sim
+		}
+		return matched / float64(len(words)), true
+	}, nil
+}
+
+// substringScore scores the first occurrence of the text in the name
+func substringScore(name, text string) float64 {
+	pos := utf8.RuneCountInString(name[:strings.Index(name, text)])
+	return float64(utf8.RuneCountInString(text)) /
+		float64(utf8.RuneCountInString(name)*(1+pos))
+}
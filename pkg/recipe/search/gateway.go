@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// loadBatch is the number of the recipes read at once to build the index
+const loadBatch = 100
+
+type indexedGateway struct {
+	recipe.StorageGateway
+	index *Index
+}
+
+// NewIndexedGateway create a storage gateway which runs the full-text
+// search on the in-process index, so it is ranked the same way whichever
+// storage is behind. The index is built from all the stored recipes and
+// kept up to date by the changes made through the gateway, the changes
+// made by other processes are not seen until the restart. The other
+// search modes and operations are passed to the storage
+func NewIndexedGateway(ctx context.Context, s recipe.StorageGateway) (recipe.StorageGateway, error) {
+	gw := &indexedGateway{StorageGateway: s, index: NewIndex()}
+
+	q := &recipe.RangeQuery{Sort: recipe.Sort{Field: recipe.SortByName}, Limit: loadBatch}
+	for {
+		recipes, err := s.GetPage(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range recipes {
+			gw.index.Add(r.IDHex(), r.Name)
+		}
+		if len(recipes) < loadBatch {
+			return gw, nil
+		}
+		q.Cursor = recipe.NewCursor(recipes[len(recipes)-1], q.Sort, false)
+	}
+}
+
+func (s *indexedGateway) Store(ctx context.Context, r *recipe.Recipe) error {
+	if err := s.StorageGateway.Store(ctx, r); err != nil {
+		return err
+	}
+	s.index.Add(r.IDHex(), r.Name)
+	return nil
+}
+
+func (s *indexedGateway) Update(ctx context.Context, r *recipe.Recipe) error {
+	if err := s.StorageGateway.Update(ctx, r); err != nil {
+		return err
+	}
+	s.index.Add(r.IDHex(), r.Name)
+	return nil
+}
+
+func (s *indexedGateway) Delete(ctx context.Context, r *recipe.Recipe) error {
+	if err := s.StorageGateway.Delete(ctx, r); err != nil {
+		return err
+	}
+	s.index.Remove(r.IDHex())
+	return nil
+}
+
+func (s *indexedGateway) DeleteByID(ctx context.Context, id string) error {
+	if err := s.StorageGateway.DeleteByID(ctx, id); err != nil {
+		return err
+	}
+	s.index.Remove(id)
+	return nil
+}
+
+func (s *indexedGateway) Search(ctx context.Context, q *recipe.SearchQuery) (*recipe.SearchResult, error) {
+	if q.Mode != recipe.SearchFullText {
+		return s.StorageGateway.Search(ctx, q)
+	}
+
+	matches := s.index.Search(q.Text, q.Fuzziness)
+	res := &recipe.SearchResult{Total: int64(len(matches))}
+	if res.Total == 0 {
+		res.Suggestions = s.index.Suggest(q.Text)
+	}
+	if q.Offset >= len(matches) {
+		return res, nil
+	}
+	matches = matches[q.Offset:]
+	if len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+
+	// The index keeps the names only, read the recipes of the page
+	for _, m := range matches {
+		r, err := s.StorageGateway.GetByID(ctx, m.ID)
+		if errors.Is(err, recipe.ErrNotFound) {
+			// Deleted by another process
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Hits = append(res.Hits, &recipe.SearchHit{Recipe: r, Score: m.Score})
+	}
+	return res, nil
+}
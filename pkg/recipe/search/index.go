@@ -0,0 +1,187 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// Parameters of the BM25 ranking
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Match is the document found in the index
+type Match struct {
+	ID    string
+	Name  string
+	Score float64
+}
+
+// document is the indexed text
+type document struct {
+	name   string
+	length int
+	terms  map[string]int
+}
+
+// Index is a thread-safe in-memory inverted index of the recipe names
+// ranked by BM25
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*document
+	postings map[string]map[string]int
+	length   int
+	// words are the names split into the words as they are, to suggest
+	words recipe.Vocabulary
+}
+
+// NewIndex create an empty index
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]*document),
+		postings: make(map[string]map[string]int),
+		words:    recipe.Vocabulary{},
+	}
+}
+
+// Len returns the number of the indexed documents
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Add indexes the name of the document, replacing the indexed one
+func (x *Index) Add(id, name string) {
+	id = strings.ToLower(id)
+	doc := &document{name: name, terms: make(map[string]int)}
+	for _, term := range Tokenize(name) {
+		doc.terms[term]++
+		doc.length++
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(id)
+	x.docs[id] = doc
+	x.length += doc.length
+	x.words.Add(name)
+	for term, n := range doc.terms {
+		if x.postings[term] == nil {
+			x.postings[term] = make(map[string]int)
+		}
+		x.postings[term][id] = n
+	}
+}
+
+// Remove drops the document from the index
+func (x *Index) Remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(strings.ToLower(id))
+}
+
+func (x *Index) remove(id string) {
+	doc, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	x.length -= doc.length
+	x.words.Remove(doc.name)
+	delete(x.docs, id)
+}
+
+// Search returns the documents containing any term of the text, sorted by
+// the descending BM25 score, then by name and ID. With the fuzziness (see
+// recipe.SearchQuery) the terms also match the indexed terms close to
+// them, weighted by the similarity
+func (x *Index) Search(text string, fuzziness int) []*Match {
+	terms := map[string]bool{}
+	for _, term := range Tokenize(text) {
+		terms[term] = true
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(x.docs))
+	avgLength := float64(x.length) / n
+	scores := map[string]float64{}
+	for term := range terms {
+		// The best of the close terms counts for every document
+		best := map[string]float64{}
+		for indexed, sim := range x.closeTerms(term, fuzziness) {
+			posting := x.postings[indexed]
+			df := float64(len(posting))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for id, tf := range posting {
+				norm := 1 - bm25B
+				if avgLength > 0 {
+					norm += bm25B * float64(x.docs[id].length) / avgLength
+				}
+				f := float64(tf)
+				if score := sim * idf * f * (bm25K1 + 1) / (f + bm25K1*norm); score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+		}
+	}
+
+	matches := make([]*Match, 0, len(scores))
+	for id, score := range scores {
+		matches = append(matches, &Match{ID: id, Name: x.docs[id].name, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return matches
+}
+
+// closeTerms returns the indexed terms matching the term with the
+// fuzziness and their similarity to it
+func (x *Index) closeTerms(term string, fuzziness int) map[string]float64 {
+	if fuzziness == 0 {
+		if _, ok := x.postings[term]; ok {
+			return map[string]float64{term: 1}
+		}
+		return nil
+	}
+	terms := map[string]float64{}
+	for indexed := range x.postings {
+		if sim, ok := recipe.FuzzyMatch(term, []string{indexed}, fuzziness); ok {
+			terms[indexed] = sim
+		}
+	}
+	return terms
+}
+
+// Suggest returns the indexed words close to the unknown words of the text
+func (x *Index) Suggest(text string) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.words.Suggest(text)
+}
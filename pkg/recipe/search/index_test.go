@@ -0,0 +1,157 @@
+package search_test
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/search"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var _ = Describe("Tokenize", func() {
+	DescribeTable("should stem the words",
+		func(word, stem string) {
+			Expect(search.Stem(word)).To(Equal(stem))
+		},
+		Entry("plural", "caresses", "caress"),
+		Entry("plural -ies", "ponies", "poni"),
+		Entry("plural -oes", "tomatoes", "tomato"),
+		Entry("-ing with double consonant", "hopping", "hop"),
+		Entry("-ed", "grilled", "grill"),
+		Entry("-ational", "relational", "relat"),
+		Entry("-ization", "generalization", "gener"),
+		Entry("-y", "happy", "happi"),
+		Entry("short", "is", "is"),
+		Entry("not English", "crème", "crème"),
+	)
+
+	It("should drop the stop words", func() {
+		Expect(search.Tokenize("Soup of the Day with Roasted Peppers")).
+			To(Equal([]string{"soup", "dai", "roast", "pepper"}))
+	})
+})
+
+var _ = Describe("Index", func() {
+	var index *search.Index
+
+	BeforeEach(func() {
+		index = search.NewIndex()
+		index.Add("1", "Tomato Soup")
+		index.Add("2", "Grilled Tomatoes with Tomato Salsa")
+		index.Add("3", "Mushroom Soup with Cream and Herbs")
+		index.Add("4", "Pasta")
+	})
+
+	ids := func(matches []*search.Match) []string {
+		var ids []string
+		for _, m := range matches {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+
+	It("should find the documents by the stemmed terms", func() {
+		Expect(ids(index.Search("tomatoes", 0))).To(ConsistOf("1", "2"))
+		Expect(index.Search("the", 0)).To(BeEmpty())
+	})
+
+	It("should rank the documents by BM25", func() {
+		matches := index.Search("tomato soup", 0)
+		// Both terms in a short name beat one term
+		Expect(ids(matches)).To(Equal([]string{"1", "2", "3"}))
+		Expect(matches[0].Score).To(BeNumerically(">", matches[1].Score))
+		// The rare term and the short name rank higher
+		Expect(ids(index.Search("soup cream", 0))[0]).To(Equal("3"))
+	})
+
+	It("should match the close terms with the fuzziness", func() {
+		Expect(index.Search("mushrom", 0)).To(BeEmpty())
+		Expect(ids(index.Search("mushrom", recipe.FuzzyAuto))).To(Equal([]string{"3"}))
+		Expect(index.Suggest("mushrom")).To(Equal([]string{"mushroom"}))
+	})
+
+	It("should replace and remove the documents", func() {
+		index.Add("4", "Tomato Pasta")
+		Expect(ids(index.Search("pasta", 0))).To(Equal([]string{"4"}))
+		Expect(ids(index.Search("tomato", 0))).To(ContainElement("4"))
+
+		index.Remove("4")
+		Expect(index.Search("pasta", 0)).To(BeEmpty())
+		Expect(index.Suggest("pastas")).To(BeEmpty())
+		Expect(index.Len()).To(Equal(3))
+	})
+})
+
+// objectIDStorage generates the native ObjectIds as the MongoDB gateways do
+type objectIDStorage struct {
+	recipe.StorageGateway
+}
+
+func (s *objectIDStorage) Store(ctx context.Context, r *recipe.Recipe) error {
+	r.ID = bson.NewObjectId()
+	return s.StorageGateway.Store(ctx, r)
+}
+
+var _ = Describe("IndexedGateway", func() {
+	var (
+		ctx     = context.Background()
+		storage recipe.StorageGateway
+	)
+
+	fulltext := func(text string) []string {
+		res, err := storage.Search(ctx, &recipe.SearchQuery{Text: text, Mode: recipe.SearchFullText, Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, h := range res.Hits {
+			names = append(names, h.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		// The recipes stored before the index are loaded by the gateway
+		mem := gateways.NewMemoryGateway()
+		Expect(mem.Store(ctx, &recipe.Recipe{Name: "Tomato Soup"})).To(Succeed())
+
+		var err error
+		storage, err = search.NewIndexedGateway(ctx, mem)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should keep the index up to date", func() {
+		Expect(fulltext("soups")).To(Equal([]string{"Tomato Soup"}))
+
+		r := &recipe.Recipe{Name: "Pea Soup"}
+		Expect(storage.Store(ctx, r)).To(Succeed())
+		Expect(fulltext("soup")).To(ConsistOf("Tomato Soup", "Pea Soup"))
+
+		r.Name = "Pea Salad"
+		Expect(storage.Update(ctx, r)).To(Succeed())
+		Expect(fulltext("soup")).To(Equal([]string{"Tomato Soup"}))
+		Expect(fulltext("salads")).To(Equal([]string{"Pea Salad"}))
+
+		Expect(storage.DeleteByID(ctx, r.IDHex())).To(Succeed())
+		Expect(fulltext("salad")).To(BeEmpty())
+	})
+
+	It("should index the recipes by the IDs generated by the storage", func() {
+		var err error
+		storage, err = search.NewIndexedGateway(ctx, &objectIDStorage{gateways.NewMemoryGateway()})
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range []string{"Pea Soup", "Onion Soup", "Fish Soup"} {
+			Expect(storage.Store(ctx, &recipe.Recipe{Name: name})).To(Succeed())
+		}
+		Expect(fulltext("soup")).To(ConsistOf("Pea Soup", "Onion Soup", "Fish Soup"))
+	})
+
+	It("should pass the other search modes to the storage", func() {
+		res, err := storage.Search(ctx, &recipe.SearchQuery{Text: "mato", Mode: recipe.SearchText, Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Total).To(Equal(int64(1)))
+	})
+})
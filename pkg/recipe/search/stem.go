@@ -0,0 +1,283 @@
+package search
+
+// Stem reduces the lower-case English word to its stem by the Porter
+// algorithm (https://tartarus.org/martin/PorterStemmer/). The words with
+// other than ASCII letters are returned as they are
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	s := &stemmer{b: []byte(word)}
+	s.k = len(s.b) - 1
+	s.step1ab()
+	if s.k > 0 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+	return string(s.b[:s.k+1])
+}
+
+// stemmer is the word being stemmed, b[0..k] is the current stem and
+// b[0..j] is the stem without the suffix found by ends
+type stemmer struct {
+	b    []byte
+	k, j int
+}
+
+// cons tells whether b[i] is a consonant
+func (s *stemmer) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.cons(i-1)
+	}
+	return true
+}
+
+// m measures the number of the vowel-consonant sequences in b[0..j]
+func (s *stemmer) m() int {
+	n, i := 0, 0
+	for {
+		if i > s.j {
+			return n
+		}
+		if !s.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+	for {
+		for {
+			if i > s.j {
+				return n
+			}
+			if s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+		for {
+			if i > s.j {
+				return n
+			}
+			if !s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+// vowelInStem tells whether b[0..j] contains a vowel
+func (s *stemmer) vowelInStem() bool {
+	for i := 0; i <= s.j; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doublec tells whether b[i-1..i] is a double consonant
+func (s *stemmer) doublec(i int) bool {
+	return i >= 1 && s.b[i] == s.b[i-1] && s.cons(i)
+}
+
+// cvc tells whether b[i-2..i] is consonant-vowel-consonant and the last
+// consonant is not w, x or y, such as in hop or cav(e)
+func (s *stemmer) cvc(i int) bool {
+	if i < 2 || !s.cons(i) || s.cons(i-1) || !s.cons(i-2) {
+		return false
+	}
+	switch s.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// ends tells whether b[0..k] ends with the suffix and sets j before it
+func (s *stemmer) ends(suffix string) bool {
+	l := len(suffix)
+	if l > s.k+1 || string(s.b[s.k-l+1:s.k+1]) != suffix {
+		return false
+	}
+	s.j = s.k - l
+	return true
+}
+
+// setTo replaces b[j+1..k] with the suffix
+func (s *stemmer) setTo(suffix string) {
+	s.b = append(s.b[:s.j+1], suffix...)
+	s.k = len(s.b) - 1
+}
+
+// replace replaces the found suffix if the stem is long enough
+func (s *stemmer) replace(suffix string) {
+	if s.m() > 0 {
+		s.setTo(suffix)
+	}
+}
+
+// replaceAny replaces the first found suffix of the pairs
+func (s *stemmer) replaceAny(pairs ...string) {
+	for i := 0; i < len(pairs); i += 2 {
+		if s.ends(pairs[i]) {
+			s.replace(pairs[i+1])
+			return
+		}
+	}
+}
+
+// step1ab removes the plurals and -ed or -ing
+func (s *stemmer) step1ab() {
+	if s.b[s.k] == 's' {
+		switch {
+		case s.ends("sses"):
+			s.k -= 2
+		case s.ends("ies"):
+			s.setTo("i")
+		case s.b[s.k-1] != 's':
+			s.k--
+		}
+	}
+	if s.ends("eed") {
+		if s.m() > 0 {
+			s.k--
+		}
+		return
+	}
+	if !(s.ends("ed") || s.ends("ing")) || !s.vowelInStem() {
+		return
+	}
+	s.k = s.j
+	switch {
+	case s.ends("at"):
+		s.setTo("ate")
+	case s.ends("bl"):
+		s.setTo("ble")
+	case s.ends("iz"):
+		s.setTo("ize")
+	case s.doublec(s.k):
+		switch s.b[s.k] {
+		case 'l', 's', 'z':
+		default:
+			s.k--
+		}
+	default:
+		s.j = s.k
+		if s.m() == 1 && s.cvc(s.k) {
+			s.setTo("e")
+		}
+	}
+}
+
+// step1c turns the terminal y to i when there is another vowel in the stem
+func (s *stemmer) step1c() {
+	if s.ends("y") && s.vowelInStem() {
+		s.b[s.k] = 'i'
+	}
+}
+
+// step2 maps the double suffixes to the single ones
+func (s *stemmer) step2() {
+	if s.k < 1 {
+		return
+	}
+	switch s.b[s.k-1] {
+	case 'a':
+		s.replaceAny("ational", "ate", "tional", "tion")
+	case 'c':
+		s.replaceAny("enci", "ence", "anci", "ance")
+	case 'e':
+		s.replaceAny("izer", "ize")
+	case 'l':
+		s.replaceAny("bli", "ble", "alli", "al", "entli", "ent", "eli", "e", "ousli", "ous")
+	case 'o':
+		s.replaceAny("ization", "ize", "ation", "ate", "ator", "ate")
+	case 's':
+		s.replaceAny("alism", "al", "iveness", "ive", "fulness", "ful", "ousness", "ous")
+	case 't':
+		s.replaceAny("aliti", "al", "iviti", "ive", "biliti", "ble")
+	case 'g':
+		s.replaceAny("logi", "log")
+	}
+}
+
+// step3 deals with -ic-, -full, -ness etc.
+func (s *stemmer) step3() {
+	switch s.b[s.k] {
+	case 'e':
+		s.replaceAny("icate", "ic", "ative", "", "alize", "al")
+	case 'i':
+		s.replaceAny("iciti", "ic")
+	case 'l':
+		s.replaceAny("ical", "ic", "ful", "")
+	case 's':
+		s.replaceAny("ness", "")
+	}
+}
+
+// step4 removes -ant, -ence etc. from the long enough stems
+func (s *stemmer) step4() {
+	if s.k < 1 {
+		return
+	}
+	var found bool
+	switch s.b[s.k-1] {
+	case 'a':
+		found = s.ends("al")
+	case 'c':
+		found = s.ends("ance") || s.ends("ence")
+	case 'e':
+		found = s.ends("er")
+	case 'i':
+		found = s.ends("ic")
+	case 'l':
+		found = s.ends("able") || s.ends("ible")
+	case 'n':
+		found = s.ends("ant") || s.ends("ement") || s.ends("ment") || s.ends("ent")
+	case 'o':
+		found = (s.ends("ion") && s.j >= 0 && (s.b[s.j] == 's' || s.b[s.j] == 't')) || s.ends("ou")
+	case 's':
+		found = s.ends("ism")
+	case 't':
+		found = s.ends("ate") || s.ends("iti")
+	case 'u':
+		found = s.ends("ous")
+	case 'v':
+		found = s.ends("ive")
+	case 'z':
+		found = s.ends("ize")
+	}
+	if found && s.m() > 1 {
+		s.k = s.j
+	}
+}
+
+// step5 removes the final -e and the double l of the long enough stems
+func (s *stemmer) step5() {
+	s.j = s.k
+	if s.b[s.k] == 'e' {
+		if a := s.m(); a > 1 || (a == 1 && !s.cvc(s.k-1)) {
+			s.k--
+		}
+	}
+	if s.b[s.k] == 'l' && s.doublec(s.k) && s.m() > 1 {
+		s.k--
+	}
+}
@@ -0,0 +1,23 @@
+package search
+
+import "github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+
+// stopWords are the English words too common to tell the recipes apart
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "into": true,
+	"is": true, "it": true, "of": true, "on": true, "or": true, "the": true,
+	"to": true, "with": true, "without": true,
+}
+
+// Tokenize splits the text into the lower-case words, drops the stop
+// words and stems the rest
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, word := range recipe.SearchTerms(text) {
+		if !stopWords[word] {
+			tokens = append(tokens, Stem(word))
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,92 @@
+package recipe
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxServings is the maximal number of the servings of the recipe
+const MaxServings = 100
+
+// quantitySteps are the steps the scaled quantities of the units are
+// rounded to. The ingredients without the unit are counted, e.g. eggs
+var quantitySteps = map[string]float64{
+	"":       1,
+	"pc":     1,
+	"pcs":    1,
+	"piece":  1,
+	"pieces": 1,
+	"clove":  1,
+	"cloves": 1,
+	"slice":  1,
+	"slices": 1,
+	"can":    1,
+	"cans":   1,
+	"kg":     0.05,
+	"l":      0.05,
+	"tsp":    0.25,
+	"tbsp":   0.25,
+	"cup":    0.25,
+	"cups":   0.25,
+	"lb":     0.25,
+	"oz":     0.5,
+}
+
+// RoundQuantity rounds the scaled quantity to the precision which makes
+// sense for the unit: the counted ingredients to whole numbers, grams and
+// milliliters to 5 above 100, the spoons and cups to quarters and so on.
+// The quantities of the unknown units keep two significant digits. The
+// measured quantity is never rounded to zero
+func RoundQuantity(q float64, unit string) float64 {
+	if q <= 0 {
+		return q
+	}
+
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	step, ok := quantitySteps[unit]
+	switch {
+	case unit == "g" || unit == "ml":
+		step = 0.5
+		if q >= 100 {
+			step = 5
+		} else if q >= 10 {
+			step = 1
+		}
+	case !ok:
+		step = math.Pow(10, math.Floor(math.Log10(q))-1)
+	}
+
+	rounded := math.Round(q/step) * step
+	if rounded == 0 {
+		rounded = step
+	}
+	// Drop the noise of the floating point steps, e.g. 0.15000000000000002
+	return math.Round(rounded*1e6) / 1e6
+}
+
+// Scale returns the copy of the recipe for the number of the servings with
+// the scaled and rounded quantities of the ingredients and the scaled total
+// nutrition
+func (r *Recipe) Scale(servings int) (*Recipe, error) {
+	switch {
+	case servings < 1 || servings > MaxServings:
+		return nil, fmt.Errorf("%w: servings must be from 1 to %d", ErrValidation, MaxServings)
+	case r.Servings == 0:
+		return nil, fmt.Errorf("%w: recipe has no servings to scale", ErrValidation)
+	}
+
+	scaled := *r
+	scaled.Servings = servings
+	scaled.Ingredients = make([]Ingredient, len(r.Ingredients))
+	factor := float64(servings) / float64(r.Servings)
+	for i, ingredient := range r.Ingredients {
+		ingredient.Quantity = RoundQuantity(ingredient.Quantity*factor, ingredient.Unit)
+		scaled.Ingredients[i] = ingredient
+	}
+	if r.Nutrition != nil {
+		scaled.Nutrition = r.Nutrition.Copy()
+		scaled.Nutrition.Total = r.Nutrition.Total.Scaled(factor)
+	}
+	return &scaled, nil
+}
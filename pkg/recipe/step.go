@@ -0,0 +1,52 @@
+package recipe
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the steps
+const (
+	MaxSteps       = 100
+	MaxStepTextLen = 2000
+)
+
+// Step is a cooking step. The duration is the timer of the step, if it has
+// one. The ingredients are the names of the recipe
+// ingredients used by the step
+type Step struct {
+	Text        string   `json:"text" bson:"text"`
+	Duration    Duration `json:"duration,omitempty" bson:"duration,omitempty"`
+	Ingredients []string `json:"ingredients,omitempty" bson:"ingredients,omitempty"`
+}
+
+// Validate checks the step against the ingredients of the recipe
+func (s *Step) Validate(ingredients []Ingredient) error {
+	switch {
+	case strings.TrimSpace(s.Text) == "":
+		return fmt.Errorf("text is required")
+	case utf8.RuneCountInString(s.Text) > MaxStepTextLen:
+		return fmt.Errorf("text is longer than %d", MaxStepTextLen)
+	}
+	if s.Duration < 0 {
+		return fmt.Errorf("duration cannot be negative")
+	}
+	for _, name := range s.Ingredients {
+		if findIngredient(ingredients, name) < 0 {
+			return fmt.Errorf("unknown ingredient %q", name)
+		}
+	}
+	return nil
+}
+
+// findIngredient returns the index of the ingredient by name, case-insensitive
+func findIngredient(ingredients []Ingredient, name string) int {
+	name = strings.TrimSpace(name)
+	for i := range ingredients {
+		if strings.EqualFold(strings.TrimSpace(ingredients[i].Name), name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,12 +1,45 @@
 package recipe
 
-// StorageGateway represent a data storage service
+import "context"
+
+// StorageGateway represent a data storage service. The context passed to
+// the methods carries the deadline and the cancellation to the storage
 type StorageGateway interface {
-	GetRange(start, limit uint64) ([]*Recipe, error)
-	GetByID(id string) (*Recipe, error)
-	DeleteByID(id string) error
-	Store(recipe *Recipe) error
-	Update(recipe *Recipe) error
-	Delete(recipe *Recipe) error
-	Search(pattern string) ([]*Recipe, error)
+	GetRange(ctx context.Context, start, limit uint64) ([]*Recipe, error)
+	// GetPage returns the recipes of the keyset range, see RangeQuery
+	GetPage(ctx context.Context, q *RangeQuery) ([]*Recipe, error)
+	// Count returns the number of the recipes passing the filter
+	Count(ctx context.Context, filter *Filter) (int64, error)
+	// Facets returns the facets of the recipes passing the filter
+	Facets(ctx context.Context, filter *Filter) (*Facets, error)
+	GetByID(ctx context.Context, id string) (*Recipe, error)
+	DeleteByID(ctx context.Context, id string) error
+	Store(ctx context.Context, recipe *Recipe) error
+	Update(ctx context.Context, recipe *Recipe) error
+	Delete(ctx context.Context, recipe *Recipe) error
+	// Search returns the page of the recipes found by name, see SearchQuery
+	Search(ctx context.Context, q *SearchQuery) (*SearchResult, error)
+	// Suggest returns the recipes found by the beginning of the name words,
+	// see SuggestQuery
+	Suggest(ctx context.Context, q *SuggestQuery) ([]*Suggestion, error)
+	// ApplyRating atomically adds the score to the recipe ratings
+	ApplyRating(ctx context.Context, id string, score uint8) error
+	// AddImage atomically adds the image to the recipe, unless it has
+	// MaxImages already. Update keeps the stored images of the recipe
+	AddImage(ctx context.Context, id string, img *Image) error
+	// RemoveImage atomically removes the image of the recipe and returns it
+	RemoveImage(ctx context.Context, id, imageID string) (*Image, error)
+
+	// Terms returns the taxonomy terms of the kind sorted by name, with the
+	// numbers of their recipes
+	Terms(ctx context.Context, kind TermKind) ([]*Term, error)
+	// GetTerm returns the term with the number of its recipes
+	GetTerm(ctx context.Context, kind TermKind, slug string) (*Term, error)
+	// StoreTerm stores the new term, its slug must be unique
+	StoreTerm(ctx context.Context, t *Term) error
+	// UpdateTerm replaces the term of the slug. The new slug of the renamed
+	// term replaces the old one in all the recipes
+	UpdateTerm(ctx context.Context, slug string, t *Term) error
+	// DeleteTerm deletes the term and removes it from all the recipes
+	DeleteTerm(ctx context.Context, kind TermKind, slug string) error
 }
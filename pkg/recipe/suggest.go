@@ -0,0 +1,87 @@
+package recipe
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MaxSuggestLimit is the maximal number of the suggestions at once
+const MaxSuggestLimit = 50
+
+// Suggestion is the lightweight recipe found by the beginning of its name
+type Suggestion struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SuggestQuery is the query of the suggestions as the user types. Every
+// word of the text must start a word of the name, the names are ranked by
+// popularity, then by name and ID
+type SuggestQuery struct {
+	Text  string
+	Limit int
+}
+
+// Validate checks the suggest query
+func (q *SuggestQuery) Validate() error {
+	switch {
+	case len(SearchTerms(q.Text)) == 0:
+		return fmt.Errorf("%w: no text to suggest for", ErrValidation)
+	case len(q.Text) > MaxSearchTextLength:
+		return fmt.Errorf("%w: suggest text is longer than %d", ErrValidation, MaxSearchTextLength)
+	case q.Limit < 1 || q.Limit > MaxSuggestLimit:
+		return fmt.Errorf("%w: suggest limit must be from 1 to %d", ErrValidation, MaxSuggestLimit)
+	}
+	return nil
+}
+
+// Match tells whether every word of the text starts a word of the name
+func (q *SuggestQuery) Match(name string) bool {
+	words := SearchTerms(name)
+	for _, term := range SearchTerms(q.Text) {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Popularity ranks the recipe by the average rating weighted by the
+// logarithm of the ratings count, so a few top ratings do not outweigh
+// many good ones
+func (r *Recipe) Popularity() float64 {
+	return r.AverageRating * math.Log1p(float64(r.RatingsCount))
+}
+
+// SortByPopularity sorts the recipes by the descending popularity, then
+// by name and ID
+func SortByPopularity(recipes []*Recipe) {
+	sort.SliceStable(recipes, func(i, j int) bool {
+		a, b := recipes[i], recipes[j]
+		if pa, pb := a.Popularity(), b.Popularity(); pa != pb {
+			return pa > pb
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.IDHex() < b.IDHex()
+	})
+}
+
+// Suggestions returns the suggestions of the recipes
+func Suggestions(recipes []*Recipe) []*Suggestion {
+	suggestions := make([]*Suggestion, 0, len(recipes))
+	for _, r := range recipes {
+		suggestions = append(suggestions, &Suggestion{ID: r.IDHex(), Name: r.Name})
+	}
+	return suggestions
+}
@@ -0,0 +1,193 @@
+package recipe
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits of the taxonomy
+const (
+	MaxTermNameLen        = 50
+	MaxTermDescriptionLen = 500
+	// MaxRecipeTerms is the maximal number of the terms of a kind per recipe
+	MaxRecipeTerms = 20
+)
+
+// ErrTermNotFound is returned when the taxonomy term does not exist
+var ErrTermNotFound = errors.New("term not found")
+
+// TermKind is the kind of the taxonomy terms
+type TermKind string
+
+// Kinds of the taxonomy terms
+const (
+	KindCuisine TermKind = "cuisine"
+	KindMeal    TermKind = "meal"
+	KindTag     TermKind = "tag"
+)
+
+// TermKinds are all the kinds of the terms
+var TermKinds = []TermKind{KindCuisine, KindMeal, KindTag}
+
+// ParseTermKind parses the kind of the terms given in singular or plural
+func ParseTermKind(s string) (TermKind, error) {
+	kind := TermKind(strings.TrimSuffix(strings.ToLower(s), "s"))
+	for _, known := range TermKinds {
+		if kind == known {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown kind of terms %q", ErrValidation, s)
+}
+
+// Term is the entry of the taxonomy the recipes are grouped by, such as
+// the italian cuisine, the dinner meal or the one-pot tag. The recipes
+// refer to the terms by the slug, which is unique among the terms of the
+// kind
+type Term struct {
+	Kind        TermKind `json:"kind" bson:"kind"`
+	Slug        string   `json:"slug" bson:"slug"`
+	Name        string   `json:"name" bson:"name"`
+	Description string   `json:"description,omitempty" bson:"description,omitempty"`
+	// Count is the number of the recipes of the term, it is not stored
+	Count int64 `json:"count" bson:"-"`
+}
+
+// Slugify returns the slug of the name: the lower-case letters and digits
+// with the other characters replaced by dashes, e.g. "One Pot!" is one-pot
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(name) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// Validate checks the term. The slug is made of the name if it is not
+// given, and normalized otherwise
+func (t *Term) Validate() error {
+	if _, err := ParseTermKind(string(t.Kind)); err != nil {
+		return err
+	}
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Slug == "" {
+		t.Slug = t.Name
+	}
+	t.Slug = Slugify(t.Slug)
+	switch {
+	case t.Name == "":
+		return fmt.Errorf("%w: term name is required", ErrValidation)
+	case t.Slug == "":
+		return fmt.Errorf("%w: term slug must have letters or digits", ErrValidation)
+	case utf8.RuneCountInString(t.Name) > MaxTermNameLen:
+		return fmt.Errorf("%w: term name is longer than %d", ErrValidation, MaxTermNameLen)
+	case utf8.RuneCountInString(t.Description) > MaxTermDescriptionLen:
+		return fmt.Errorf("%w: term description is longer than %d", ErrValidation, MaxTermDescriptionLen)
+	}
+	return nil
+}
+
+// termSlugs returns the field of the recipe with the slugs of the kind
+func (r *Recipe) termSlugs(kind TermKind) *[]string {
+	switch kind {
+	case KindCuisine:
+		return &r.Cuisines
+	case KindMeal:
+		return &r.Meals
+	case KindTag:
+		return &r.Tags
+	}
+	return nil
+}
+
+// Terms returns the slugs of the recipe terms of the kind
+func (r *Recipe) Terms(kind TermKind) []string {
+	if slugs := r.termSlugs(kind); slugs != nil {
+		return *slugs
+	}
+	return nil
+}
+
+// HasTerm tells whether the recipe has the term
+func (r *Recipe) HasTerm(kind TermKind, slug string) bool {
+	for _, s := range r.Terms(kind) {
+		if s == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeTerms slugifies the terms of the recipe and drops the
+// duplicates, so the terms may be given by their names
+func (r *Recipe) NormalizeTerms() {
+	for _, kind := range TermKinds {
+		slugs := r.termSlugs(kind)
+		if *slugs == nil {
+			continue
+		}
+		seen := map[string]bool{}
+		normalized := []string{}
+		for _, s := range *slugs {
+			if s = Slugify(s); s != "" && !seen[s] {
+				seen[s] = true
+				normalized = append(normalized, s)
+			}
+		}
+		*slugs = normalized
+	}
+}
+
+// ReplaceTerm replaces the term of the recipe by the new slug, or removes
+// it if the new slug is empty. It tells whether the recipe had the term
+func (r *Recipe) ReplaceTerm(kind TermKind, slug, newSlug string) bool {
+	slugs := r.termSlugs(kind)
+	if slugs == nil {
+		return false
+	}
+	replaced := false
+	merged := newSlug == "" || r.HasTerm(kind, newSlug)
+	kept := []string{}
+	for _, s := range *slugs {
+		if s == slug {
+			replaced = true
+			if merged {
+				continue
+			}
+			s = newSlug
+		}
+		kept = append(kept, s)
+	}
+	if replaced {
+		*slugs = kept
+	}
+	return replaced
+}
+
+// validateTerms checks the number of the recipe terms
+func (r *Recipe) validateTerms(v *ValidationError) {
+	for _, kind := range TermKinds {
+		field := string(kind) + "s"
+		slugs := r.Terms(kind)
+		if len(slugs) > MaxRecipeTerms {
+			v.Add(field, "more than %d terms", MaxRecipeTerms)
+		}
+		for i, s := range slugs {
+			if s == "" {
+				v.Add(fmt.Sprintf("%s[%d]", field, i), "cannot be empty")
+			}
+		}
+	}
+}
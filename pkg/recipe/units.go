@@ -0,0 +1,30 @@
+package recipe
+
+import "github.com/ashkarin/ashkarin-api-test/pkg/units"
+
+// InUnits returns the copy of the recipe with the quantities of the
+// ingredients and the temperatures of the steps converted to the system of
+// units. The converted quantities are rounded by RoundQuantity, the
+// ingredients of the unknown units and of the temperature units, which are
+// never the quantities, are kept as they are
+func (r *Recipe) InUnits(sys units.System) *Recipe {
+	converted := *r
+	converted.Ingredients = make([]Ingredient, len(r.Ingredients))
+	for i, ingredient := range r.Ingredients {
+		if u, err := units.Lookup(ingredient.Unit); err == nil && u.Dimension != units.Temperature && ingredient.Quantity > 0 {
+			density, _ := units.Density(ingredient.Name)
+			if q, to := units.ToSystem(ingredient.Quantity, u, sys, density); to != u {
+				ingredient.Quantity = RoundQuantity(q, to.Symbol)
+				ingredient.Unit = to.Symbol
+			}
+		}
+		converted.Ingredients[i] = ingredient
+	}
+
+	converted.Steps = make([]Step, len(r.Steps))
+	for i, step := range r.Steps {
+		step.Text = units.ConvertTemperatures(step.Text, sys)
+		converted.Steps[i] = step
+	}
+	return &converted
+}
@@ -1,10 +1,63 @@
 package usecases
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
 )
 
-// CreateRecipe create the recipe entry in the storage
-func CreateRecipe(s recipe.StorageGateway, r *recipe.Recipe) error {
-	return s.Store(r)
+// CreateRecipe create the recipe entry in the storage. The given ratings
+// are ignored, the recipe gets them by RateRecipe only
+func CreateRecipe(ctx context.Context, s recipe.StorageGateway, r *recipe.Recipe) error {
+	r.AverageRating, r.RatingsCount, r.RatingsSum = 0, 0, 0
+	return ImportRecipe(ctx, s, r)
+}
+
+// ImportRecipe create the recipe entry in the storage with the given
+// ratings, e.g. the recipe moved from another storage. It is meant for
+// the privileged clients only
+func ImportRecipe(ctx context.Context, s recipe.StorageGateway, r *recipe.Recipe) error {
+	if err := prepareRecipe(ctx, s, r); err != nil {
+		return err
+	}
+	r.RatingsSum = r.AverageRating * float64(r.RatingsCount)
+	return s.Store(ctx, r)
+}
+
+// prepareRecipe normalizes and validates the recipe and sets its computed
+// fields before it is stored. The given nutrition is dropped, the storage computes it if it
+// has the nutrition table (see the nutrition package). So are the given
+// allergens, the storage derives them if it has the ingredient catalog
+// (see the catalog package). The taxonomy terms of the recipe must exist.
+// The given images are dropped, they are added by AddRecipeImage only
+func prepareRecipe(ctx context.Context, s recipe.StorageGateway, r *recipe.Recipe) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.NormalizeTerms()
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	v := &recipe.ValidationError{}
+	for _, kind := range recipe.TermKinds {
+		for i, slug := range r.Terms(kind) {
+			_, err := s.GetTerm(ctx, kind, slug)
+			if errors.Is(err, recipe.ErrTermNotFound) {
+				v.Add(fmt.Sprintf("%ss[%d]", kind, i), "unknown %s %q", kind, slug)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	r.ComputeTotalTime()
+	r.Nutrition = nil
+	r.Images = nil
+	r.Classify(nil, nil, false)
+	return nil
 }
@@ -1,15 +1,17 @@
 package usecases
 
 import (
+	"context"
+
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
 )
 
 // DeleteRecipe delete the recipe entry from the storage
-func DeleteRecipe(s recipe.StorageGateway, r *recipe.Recipe) error {
-	return s.Delete(r)
+func DeleteRecipe(ctx context.Context, s recipe.StorageGateway, r *recipe.Recipe) error {
+	return s.Delete(ctx, r)
 }
 
 // DeleteRecipeByID delete the recipe entry from the storage
-func DeleteRecipeByID(s recipe.StorageGateway, id string) error {
-	return s.DeleteByID(id)
+func DeleteRecipeByID(ctx context.Context, s recipe.StorageGateway, id string) error {
+	return s.DeleteByID(ctx, id)
 }
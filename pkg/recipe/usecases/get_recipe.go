@@ -1,10 +1,12 @@
 package usecases
 
 import (
+	"context"
+
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
 )
 
 // GetRecipe get recipe by its ID
-func GetRecipe(s recipe.StorageGateway, ID string) (*recipe.Recipe, error) {
-	return s.GetByID(ID)
+func GetRecipe(ctx context.Context, s recipe.StorageGateway, ID string) (*recipe.Recipe, error) {
+	return s.GetByID(ctx, ID)
 }
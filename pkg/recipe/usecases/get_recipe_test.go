@@ -0,0 +1,25 @@
+package usecases_test
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/usecases"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetRecipe", func() {
+	It("should not find a missing recipe", func() {
+		_, err := usecases.GetRecipe(context.Background(), storage, "5b4b6b6f0000000000000000")
+		Expect(err).To(MatchError(recipe.ErrNotFound))
+	})
+
+	It("should stop when the context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := usecases.GetRecipe(ctx, storage, "5b4b6b6f0000000000000000")
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
@@ -1,10 +1,66 @@
 package usecases
 
 import (
+	"context"
+
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
 )
 
 // ListRecipes list all recipes in the storage
-func ListRecipes(s recipe.StorageGateway, start, limit uint64) ([]*recipe.Recipe, error) {
-	return s.GetRange(start, limit)
+func ListRecipes(ctx context.Context, s recipe.StorageGateway, start, limit uint64) ([]*recipe.Recipe, error) {
+	return s.GetRange(ctx, start, limit)
+}
+
+// ListRecipesPage list the page of the sorted and filtered recipes next to
+// the cursor of the query, and returns the cursors of the adjacent pages.
+// The total number and the facets of the filtered recipes are optional
+func ListRecipesPage(ctx context.Context, s recipe.StorageGateway, q recipe.RangeQuery, withTotal, withFacets bool) (*recipe.Page, error) {
+	if q.Filter != nil {
+		if err := q.Filter.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Ask for one recipe more to know whether there is the page after this one
+	limit := q.Limit
+	q.Limit = limit + 1
+	recipes, err := s.GetPage(ctx, &q)
+	if err != nil {
+		return nil, err
+	}
+
+	backward := q.Cursor != nil && q.Cursor.Backward
+	more := len(recipes) > limit
+	if more && backward {
+		recipes = recipes[1:]
+	} else if more {
+		recipes = recipes[:limit]
+	}
+
+	page := &recipe.Page{Recipes: recipes}
+	if len(recipes) > 0 {
+		first, last := recipes[0], recipes[len(recipes)-1]
+		// Moving forward there is the page before unless this is the first
+		// one, moving backward there is always the page after
+		if (backward && more) || (!backward && q.Cursor != nil) {
+			page.Prev = recipe.NewCursor(first, q.Sort, true)
+		}
+		if (!backward && more) || backward {
+			page.Next = recipe.NewCursor(last, q.Sort, false)
+		}
+	}
+
+	if withTotal {
+		total, err := s.Count(ctx, q.Filter)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+	if withFacets {
+		if page.Facets, err = s.Facets(ctx, q.Filter); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
 }
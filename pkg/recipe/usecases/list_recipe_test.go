@@ -0,0 +1,112 @@
+package usecases_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/usecases"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListRecipesPage", func() {
+	ctx := context.Background()
+	var stored []*recipe.Recipe
+
+	names := func(page *recipe.Page) []string {
+		var names []string
+		for _, r := range page.Recipes {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		stored = nil
+		entries := []struct {
+			name     string
+			prepTime time.Duration
+			rating   float64
+		}{
+			{"E", time.Hour, 3}, {"B", 20 * time.Minute, 5}, {"G", 10 * time.Minute, 1}, {"A", 45 * time.Minute, 4},
+			{"D", 90 * time.Minute, 3}, {"C", 15 * time.Minute, 2}, {"F", 30 * time.Minute, 3},
+		}
+		for _, e := range entries {
+			r := &recipe.Recipe{Name: e.name, PrepTime: recipe.Duration(e.prepTime), Difficulty: recipe.Easy, AverageRating: e.rating, RatingsCount: 1}
+			Expect(usecases.ImportRecipe(ctx, storage, r)).To(Succeed())
+		}
+		all, err := usecases.ListRecipes(ctx, storage, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		stored = all
+	})
+
+	AfterEach(func() {
+		for _, r := range stored {
+			Expect(usecases.DeleteRecipe(ctx, storage, r)).To(Succeed())
+		}
+	})
+
+	It("should walk the pages forward and backward", func() {
+		q := recipe.RangeQuery{Sort: recipe.Sort{Field: recipe.SortByName}, Limit: 3}
+		page, err := usecases.ListRecipesPage(ctx, storage, q, true, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"A", "B", "C"}))
+		Expect(page.Prev).To(BeNil())
+		Expect(*page.Total).To(Equal(int64(7)))
+
+		q.Cursor = page.Next
+		page, err = usecases.ListRecipesPage(ctx, storage, q, false, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"D", "E", "F"}))
+		Expect(page.Total).To(BeNil())
+
+		q.Cursor = page.Next
+		page, err = usecases.ListRecipesPage(ctx, storage, q, false, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"G"}))
+		Expect(page.Next).To(BeNil())
+
+		q.Cursor = page.Prev
+		page, err = usecases.ListRecipesPage(ctx, storage, q, false, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"D", "E", "F"}))
+
+		q.Cursor = page.Prev
+		page, err = usecases.ListRecipesPage(ctx, storage, q, false, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"A", "B", "C"}))
+		Expect(page.Prev).To(BeNil())
+		Expect(page.Next).NotTo(BeNil())
+	})
+
+	It("should keep the equal values in a stable order", func() {
+		q := recipe.RangeQuery{Sort: recipe.Sort{Field: recipe.SortByAverageRating, Desc: true}, Limit: 3}
+		page, err := usecases.ListRecipesPage(ctx, storage, q, false, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"B", "A", "F"}))
+
+		// The recipes rated 3 are split between the pages
+		q.Cursor, err = recipe.DecodeCursor(page.Next.Encode(), q.Sort)
+		Expect(err).NotTo(HaveOccurred())
+		page, err = usecases.ListRecipesPage(ctx, storage, q, false, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"D", "E", "C"}))
+	})
+
+	It("should sort by the preparation time as a duration", func() {
+		q := recipe.RangeQuery{Sort: recipe.Sort{Field: recipe.SortByPrepTime}, Limit: 10}
+		page, err := usecases.ListRecipesPage(ctx, storage, q, false, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(page)).To(Equal([]string{"G", "C", "B", "F", "A", "E", "D"}))
+	})
+
+	It("should reject the cursor of another sort order", func() {
+		q := recipe.RangeQuery{Sort: recipe.Sort{Field: recipe.SortByName}, Limit: 3}
+		page, err := usecases.ListRecipesPage(ctx, storage, q, false, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = recipe.DecodeCursor(page.Next.Encode(), recipe.Sort{Field: recipe.SortByPrepTime})
+		Expect(err).To(MatchError(recipe.ErrValidation))
+	})
+})
@@ -0,0 +1,66 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// PatchFunc applies the patch to the JSON document of the recipe
+type PatchFunc func(doc []byte) ([]byte, error)
+
+// PatchRecipe applies the patch to the stored recipe. The fields untouched
+// by the patch keep the stored values, and the update fails if the recipe
+// changed after it was read. The ID, the version and the ratings cannot be
+// patched. If the version is given, the stored recipe must have it
+func PatchRecipe(ctx context.Context, s recipe.StorageGateway, id string, version int64, patch PatchFunc) (*recipe.Recipe, error) {
+	stored, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != stored.Version {
+		return nil, fmt.Errorf("%w: version %d is stale", recipe.ErrConflict, version)
+	}
+
+	doc, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := patch(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", recipe.ErrValidation, err)
+	}
+	updated := &recipe.Recipe{}
+	if err := json.Unmarshal(patched, updated); err != nil {
+		return nil, fmt.Errorf("%w: %v", recipe.ErrValidation, err)
+	}
+
+	v := &recipe.ValidationError{}
+	if updated.IDHex() != stored.IDHex() {
+		v.Add("_id", "cannot be patched")
+	}
+	if updated.Version != stored.Version {
+		v.Add("version", "cannot be patched")
+	}
+	if updated.AverageRating != stored.AverageRating {
+		v.Add("averageRating", "cannot be patched, rate the recipe instead")
+	}
+	if updated.RatingsCount != stored.RatingsCount {
+		v.Add("ratingsCount", "cannot be patched, rate the recipe instead")
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := prepareRecipe(ctx, s, updated); err != nil {
+		return nil, err
+	}
+
+	updated.ID = stored.ID
+	if err := s.Update(ctx, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,71 @@
+package usecases_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/usecases"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateRecipe", func() {
+	var r *recipe.Recipe
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		r = &recipe.Recipe{Name: "Rated", PrepTime: recipe.Duration(20 * time.Minute), Difficulty: recipe.Easy}
+		Expect(usecases.CreateRecipe(ctx, storage, r)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(usecases.DeleteRecipe(ctx, storage, r)).To(Succeed())
+	})
+
+	It("should reject scores out of range", func() {
+		err := usecases.RateRecipeByID(ctx, storage, r.IDHex(), 6)
+		Expect(err).To(MatchError(recipe.ErrValidation))
+	})
+
+	It("should not lose concurrent ratings", func() {
+		const ratings = 500
+
+		var wg sync.WaitGroup
+		for i := 0; i < ratings; i++ {
+			wg.Add(1)
+			go func(score uint8) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(usecases.RateRecipeByID(ctx, storage, r.IDHex(), score)).To(Succeed())
+			}(uint8(i%5 + 1))
+		}
+		wg.Wait()
+
+		obtained, err := usecases.GetRecipe(ctx, storage, r.IDHex())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obtained.RatingsCount).To(Equal(int64(ratings)))
+		Expect(obtained.AverageRating).To(Equal(3.0))
+	})
+
+	It("should keep the average exact for equal scores", func() {
+		const ratings = 300
+
+		var wg sync.WaitGroup
+		for i := 0; i < ratings; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(usecases.RateRecipeByID(ctx, storage, r.IDHex(), 4)).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		obtained, err := usecases.GetRecipe(ctx, storage, r.IDHex())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obtained.RatingsCount).To(Equal(int64(ratings)))
+		Expect(obtained.AverageRating).To(Equal(4.0))
+	})
+})
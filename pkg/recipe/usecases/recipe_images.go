@@ -0,0 +1,74 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/blob"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/images"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ListRecipeImages returns the images of the recipe
+func ListRecipeImages(ctx context.Context, s recipe.StorageGateway, id string) ([]recipe.Image, error) {
+	r, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Images == nil {
+		return []recipe.Image{}, nil
+	}
+	return r.Images, nil
+}
+
+// AddRecipeImage resizes the image to the variants, puts them to the blob
+// store and adds the image to the recipe. The blobs are deleted again if
+// the recipe does not get the image
+func AddRecipeImage(ctx context.Context, s recipe.StorageGateway, blobs blob.Store, id string, data []byte) (*recipe.Image, error) {
+	r, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Images) >= recipe.MaxImages {
+		return nil, recipe.ErrTooManyImages
+	}
+	resized, err := images.Process(data)
+	if err != nil {
+		return nil, err
+	}
+
+	img := &recipe.Image{ID: bson.NewObjectId().Hex(), Variants: map[string]recipe.ImageVariant{}}
+	for _, v := range resized {
+		key := images.Key(r.IDHex(), img.ID, v.Name)
+		img.Variants[v.Name] = recipe.ImageVariant{
+			URL:         blobs.URL(key),
+			Key:         key,
+			ContentType: images.ContentType,
+			Width:       v.Width,
+			Height:      v.Height,
+		}
+		if err = blobs.Put(ctx, key, images.ContentType, bytes.NewReader(v.Data)); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = s.AddImage(ctx, id, img)
+	}
+	if err != nil {
+		images.DeleteBlobs(ctx, blobs, *img)
+		return nil, err
+	}
+	return img, nil
+}
+
+// RemoveRecipeImage removes the image from the recipe and deletes its
+// variants from the blob store
+func RemoveRecipeImage(ctx context.Context, s recipe.StorageGateway, blobs blob.Store, id, imageID string) error {
+	img, err := s.RemoveImage(ctx, id, imageID)
+	if err != nil {
+		return err
+	}
+	images.DeleteBlobs(ctx, blobs, *img)
+	return nil
+}
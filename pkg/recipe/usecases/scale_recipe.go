@@ -0,0 +1,17 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// ScaleRecipe get recipe by its ID with the ingredients scaled to the
+// number of the servings
+func ScaleRecipe(ctx context.Context, s recipe.StorageGateway, ID string, servings int) (*recipe.Recipe, error) {
+	r, err := s.GetByID(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.Scale(servings)
+}
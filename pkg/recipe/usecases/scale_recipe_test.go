@@ -0,0 +1,64 @@
+package usecases_test
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/usecases"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ScaleRecipe", func() {
+	var r *recipe.Recipe
+
+	BeforeEach(func() {
+		r = &recipe.Recipe{
+			Name:       "Pancakes",
+			Difficulty: recipe.Easy,
+			Servings:   3,
+			Ingredients: []recipe.Ingredient{
+				{Name: "eggs", Quantity: 2},
+				{Name: "flour", Quantity: 250, Unit: "g"},
+				{Name: "milk", Quantity: 0.5, Unit: "l"},
+				{Name: "sugar", Quantity: 1, Unit: "tbsp"},
+				{Name: "salt", Notes: "a pinch"},
+			},
+		}
+		Expect(usecases.CreateRecipe(context.Background(), storage, r)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(usecases.DeleteRecipeByID(context.Background(), storage, r.IDHex())).To(Succeed())
+	})
+
+	It("should scale and round the quantities", func() {
+		scaled, err := usecases.ScaleRecipe(context.Background(), storage, r.IDHex(), 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scaled.Servings).To(Equal(2))
+		Expect(scaled.Ingredients).To(Equal([]recipe.Ingredient{
+			{Name: "eggs", Quantity: 1},
+			{Name: "flour", Quantity: 165, Unit: "g"},
+			{Name: "milk", Quantity: 0.35, Unit: "l"},
+			{Name: "sugar", Quantity: 0.75, Unit: "tbsp"},
+			{Name: "salt", Notes: "a pinch"},
+		}))
+
+		// The stored recipe is not changed
+		stored, err := usecases.GetRecipe(context.Background(), storage, r.IDHex())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Servings).To(Equal(3))
+		Expect(stored.Ingredients[0].Quantity).To(Equal(2.0))
+	})
+
+	It("should reject the invalid servings", func() {
+		_, err := usecases.ScaleRecipe(context.Background(), storage, r.IDHex(), 0)
+		Expect(err).To(MatchError(recipe.ErrValidation))
+
+		unscalable := &recipe.Recipe{Name: "Unknown servings", Difficulty: recipe.Easy}
+		Expect(usecases.CreateRecipe(context.Background(), storage, unscalable)).To(Succeed())
+		defer usecases.DeleteRecipeByID(context.Background(), storage, unscalable.IDHex())
+		_, err = usecases.ScaleRecipe(context.Background(), storage, unscalable.IDHex(), 2)
+		Expect(err).To(MatchError(recipe.ErrValidation))
+	})
+})
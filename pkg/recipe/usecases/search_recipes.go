@@ -1,10 +1,19 @@
 package usecases
 
 import (
+	"context"
+
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
 )
 
-// SearchRecipes get recipes which names match the given pattern
-func SearchRecipes(s recipe.StorageGateway, namePattern string) ([]*recipe.Recipe, error) {
-	return s.Search(namePattern)
+// SearchRecipes get the page of the recipes which names match the query,
+// sorted by relevance
+func SearchRecipes(ctx context.Context, s recipe.StorageGateway, q recipe.SearchQuery) (*recipe.SearchResult, error) {
+	if q.Mode == "" {
+		q.Mode = recipe.SearchText
+	}
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return s.Search(ctx, &q)
 }
@@ -0,0 +1,16 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// SuggestRecipes get the most popular recipes which name words start with
+// the words of the query, to complete the name as the user types
+func SuggestRecipes(ctx context.Context, s recipe.StorageGateway, q recipe.SuggestQuery) ([]*recipe.Suggestion, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return s.Suggest(ctx, &q)
+}
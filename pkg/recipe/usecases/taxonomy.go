@@ -0,0 +1,56 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+)
+
+// ListTerms returns the taxonomy terms of the kind with the numbers of their recipes
+func ListTerms(ctx context.Context, s recipe.StorageGateway, kind recipe.TermKind) ([]*recipe.Term, error) {
+	terms, err := s.Terms(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	if terms == nil {
+		terms = []*recipe.Term{}
+	}
+	return terms, nil
+}
+
+// GetTerm returns the taxonomy term with the number of its recipes
+func GetTerm(ctx context.Context, s recipe.StorageGateway, kind recipe.TermKind, slug string) (*recipe.Term, error) {
+	return s.GetTerm(ctx, kind, slug)
+}
+
+// CreateTerm create the taxonomy term in the storage
+func CreateTerm(ctx context.Context, s recipe.StorageGateway, t *recipe.Term) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	t.Count = 0
+	return s.StoreTerm(ctx, t)
+}
+
+// UpdateTerm replaces the taxonomy term of the slug. The term is renamed
+// in all the recipes if its slug changes, the slug is made of the new name
+// if it is not given
+func UpdateTerm(ctx context.Context, s recipe.StorageGateway, slug string, t *recipe.Term) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if err := s.UpdateTerm(ctx, slug, t); err != nil {
+		return err
+	}
+	updated, err := s.GetTerm(ctx, t.Kind, t.Slug)
+	if err != nil {
+		return err
+	}
+	*t = *updated
+	return nil
+}
+
+// DeleteTerm deletes the taxonomy term and removes it from all the recipes
+func DeleteTerm(ctx context.Context, s recipe.StorageGateway, kind recipe.TermKind, slug string) error {
+	return s.DeleteTerm(ctx, kind, slug)
+}
@@ -1,10 +1,17 @@
 package usecases
 
 import (
+	"context"
+
 	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
 )
 
-// UpdateRecipe update recipe entry in the storage
-func UpdateRecipe(s recipe.StorageGateway, r *recipe.Recipe) error {
-	return s.Update(r)
+// UpdateRecipe update recipe entry in the storage. The given ratings are
+// ignored, the storage keeps the stored ones
+func UpdateRecipe(ctx context.Context, s recipe.StorageGateway, r *recipe.Recipe) error {
+	r.AverageRating, r.RatingsCount, r.RatingsSum = 0, 0, 0
+	if err := prepareRecipe(ctx, s, r); err != nil {
+		return err
+	}
+	return s.Update(ctx, r)
 }
@@ -0,0 +1,40 @@
+package usecases_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe"
+	"github.com/ashkarin/ashkarin-api-test/pkg/recipe/gateways"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var storage recipe.StorageGateway
+
+func TestUsecases(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Usecases Suite")
+}
+
+var _ = BeforeSuite(func() {
+	// Open a gateway to the storage, the in-memory one is used unless
+	// DB_GATEWAY asks for MongoDB
+	dbopts := gateways.MongoOptions{
+		Server:     "mongodb",
+		Port:       "27017",
+		Database:   "test_db",
+		Collection: "testusecases",
+	}
+
+	var err error
+	switch os.Getenv("DB_GATEWAY") {
+	case "mongodb":
+		storage, err = gateways.NewMongoDbGateway(dbopts)
+	case "mongo-driver":
+		storage, err = gateways.NewMongoDriverGateway(dbopts)
+	default:
+		storage = gateways.NewMemoryGateway()
+	}
+	Expect(err).NotTo(HaveOccurred())
+})
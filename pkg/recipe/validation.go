@@ -0,0 +1,52 @@
+package recipe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is the error of the recipe field given by its JSON path,
+// e.g. ingredients[2]
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is the list of the field errors of the recipe. It is an
+// ErrValidation, so check it with errors.Is, and get the fields with errors.As
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return fmt.Sprintf("%v: %s", ErrValidation, strings.Join(messages, "; "))
+}
+
+// Unwrap makes the field errors an ErrValidation
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// Add adds the error of the field
+func (e *ValidationError) Add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// AddError adds the error of the field, the ErrValidation prefix of the
+// wrapped errors is dropped from the message
+func (e *ValidationError) AddError(field string, err error) {
+	message := strings.TrimPrefix(err.Error(), ErrValidation.Error()+": ")
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns the error if there are field errors, and nil otherwise
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,50 @@
+package units
+
+import "strings"
+
+// densities are the densities of the common ingredients in grams per
+// milliliter, by the word of the ingredient name
+var densities = map[string]float64{
+	"water":          1,
+	"milk":           1.03,
+	"cream":          1.01,
+	"buttermilk":     1.03,
+	"yogurt":         1.03,
+	"yoghurt":        1.03,
+	"oil":            0.92,
+	"butter":         0.91,
+	"honey":          1.42,
+	"syrup":          1.33,
+	"flour":          0.53,
+	"sugar":          0.85,
+	"brown sugar":    0.93,
+	"icing sugar":    0.56,
+	"powdered sugar": 0.56,
+	"salt":           1.2,
+	"rice":           0.85,
+	"oats":           0.41,
+	"cocoa":          0.42,
+	"cornstarch":     0.54,
+	"baking powder":  0.9,
+	"baking soda":    0.92,
+	"breadcrumbs":    0.45,
+	"cheese":         0.45,
+	"parmesan":       0.4,
+}
+
+// Density returns the density of the ingredient in grams per milliliter.
+// The longest known name contained in the ingredient name wins, e.g.
+// "light brown sugar" is brown sugar
+func Density(ingredient string) (float64, bool) {
+	name := " " + strings.Join(strings.Fields(strings.ToLower(ingredient)), " ") + " "
+	best := ""
+	for known := range densities {
+		if len(known) > len(best) && strings.Contains(name, " "+known+" ") {
+			best = known
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	return densities[best], true
+}
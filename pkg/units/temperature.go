@@ -0,0 +1,32 @@
+package units
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// temperatures are the temperatures in the text, such as 180°C or 350 °F
+var temperatures = regexp.MustCompile(`(-?\d+(?:\.\d+)?)\s?°\s?([CcFf])\b`)
+
+// ConvertTemperatures converts the temperatures in the text, such as the
+// oven temperatures in the cooking steps, to the system. The converted
+// temperatures are rounded to whole degrees
+func ConvertTemperatures(text string, sys System) string {
+	return temperatures.ReplaceAllStringFunc(text, func(match string) string {
+		m := temperatures.FindStringSubmatch(match)
+		v, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return match
+		}
+		from, err := Lookup("°" + m[2])
+		if err != nil {
+			return match
+		}
+		converted, to := ToSystem(v, from, sys, 0)
+		if to == from {
+			return match
+		}
+		return strconv.FormatFloat(math.Round(converted), 'f', -1, 64) + to.Symbol
+	})
+}
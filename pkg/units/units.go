@@ -0,0 +1,208 @@
+package units
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors of the units
+var (
+	ErrUnknownUnit  = errors.New("unknown unit")
+	ErrIncompatible = errors.New("incompatible units")
+)
+
+// Dimension is the kind of the quantity measured by the unit
+type Dimension int
+
+// Dimensions of the units
+const (
+	Mass Dimension = iota + 1
+	Volume
+	Count
+	Temperature
+)
+
+// System is the system of units
+type System string
+
+// Systems of units. The units of any system, such as spoons and pieces,
+// are used by both metric and imperial recipes
+const (
+	AnySystem System = ""
+	Metric    System = "metric"
+	Imperial  System = "imperial"
+)
+
+// ParseSystem parses the name of the metric or imperial system
+func ParseSystem(s string) (System, error) {
+	switch sys := System(strings.ToLower(s)); sys {
+	case Metric, Imperial:
+		return sys, nil
+	}
+	return AnySystem, fmt.Errorf("unknown system of units %q", s)
+}
+
+// Unit is the unit of measure
+type Unit struct {
+	Symbol    string
+	Dimension Dimension
+	System    System
+	// Factor is the size of the unit in the base units of its dimension:
+	// grams, milliliters or pieces. The temperatures are not scaled
+	Factor float64
+}
+
+// Units of measure. The volumes are the US customary ones
+var (
+	Milligram  = Unit{"mg", Mass, Metric, 0.001}
+	Gram       = Unit{"g", Mass, Metric, 1}
+	Kilogram   = Unit{"kg", Mass, Metric, 1000}
+	Ounce      = Unit{"oz", Mass, Imperial, 28.349523125}
+	Pound      = Unit{"lb", Mass, Imperial, 453.59237}
+	Milliliter = Unit{"ml", Volume, Metric, 1}
+	Centiliter = Unit{"cl", Volume, Metric, 10}
+	Deciliter  = Unit{"dl", Volume, Metric, 100}
+	Liter      = Unit{"l", Volume, Metric, 1000}
+	Teaspoon   = Unit{"tsp", Volume, AnySystem, 4.92892159375}
+	Tablespoon = Unit{"tbsp", Volume, AnySystem, 14.78676478125}
+	FluidOunce = Unit{"fl oz", Volume, Imperial, 29.5735295625}
+	Cup        = Unit{"cup", Volume, Imperial, 236.5882365}
+	Pint       = Unit{"pint", Volume, Imperial, 473.176473}
+	Quart      = Unit{"quart", Volume, Imperial, 946.352946}
+	Gallon     = Unit{"gallon", Volume, Imperial, 3785.411784}
+	Piece      = Unit{"pcs", Count, AnySystem, 1}
+	Celsius    = Unit{"°C", Temperature, Metric, 1}
+	Fahrenheit = Unit{"°F", Temperature, Imperial, 1}
+)
+
+// aliases are the names of the units, lower-case
+var aliases = map[string]Unit{}
+
+func init() {
+	names := map[Unit][]string{
+		Milligram:  {"milligram", "milligrams"},
+		Gram:       {"gram", "grams", "gr"},
+		Kilogram:   {"kilogram", "kilograms", "kilo", "kilos"},
+		Ounce:      {"ounce", "ounces"},
+		Pound:      {"lbs", "pound", "pounds"},
+		Milliliter: {"milliliter", "milliliters", "millilitre", "millilitres"},
+		Centiliter: {"centiliter", "centiliters", "centilitre", "centilitres"},
+		Deciliter:  {"deciliter", "deciliters", "decilitre", "decilitres"},
+		Liter:      {"liter", "liters", "litre", "litres"},
+		Teaspoon:   {"tsp.", "teaspoon", "teaspoons"},
+		Tablespoon: {"tbsp.", "tbs", "tablespoon", "tablespoons"},
+		FluidOunce: {"floz", "fl. oz", "fluid ounce", "fluid ounces"},
+		Cup:        {"cups"},
+		Pint:       {"pints", "pt"},
+		Quart:      {"quarts", "qt"},
+		Gallon:     {"gallons", "gal"},
+		Piece:      {"pc", "piece", "pieces"},
+		// The bare c and f are not the degrees, the c is rather the cup
+		Celsius:    {"celsius"},
+		Fahrenheit: {"fahrenheit"},
+	}
+	for u, list := range names {
+		aliases[strings.ToLower(u.Symbol)] = u
+		for _, name := range list {
+			aliases[name] = u
+		}
+	}
+}
+
+// Lookup returns the unit by its symbol or name, case-insensitive
+func Lookup(name string) (Unit, error) {
+	u, ok := aliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Unit{}, fmt.Errorf("%w: %q", ErrUnknownUnit, name)
+	}
+	return u, nil
+}
+
+// Convert converts the value between the units. The mass and the volume
+// are converted by the density in grams per milliliter, if it is known
+func Convert(v float64, from, to Unit, density float64) (float64, error) {
+	switch {
+	case from.Dimension == to.Dimension && from.Dimension == Temperature:
+		if from == to {
+			return v, nil
+		}
+		if from == Celsius {
+			return v*9/5 + 32, nil
+		}
+		return (v - 32) * 5 / 9, nil
+	case from.Dimension == to.Dimension:
+		return v * from.Factor / to.Factor, nil
+	case from.Dimension == Volume && to.Dimension == Mass && density > 0:
+		return v * from.Factor * density / to.Factor, nil
+	case from.Dimension == Mass && to.Dimension == Volume && density > 0:
+		return v * from.Factor / density / to.Factor, nil
+	}
+	return 0, fmt.Errorf("%w: %s and %s", ErrIncompatible, from.Symbol, to.Symbol)
+}
+
+// ToSystem converts the value to the unit of the system which fits its
+// size, e.g. 1500 g to 1.5 kg or 3.3 lb. The units of the system and the
+// units of any system are kept. With the density known, the metric system
+// weighs the ingredients and the imperial one measures them by volume, as
+// the cooks of these systems do
+func ToSystem(v float64, u Unit, sys System, density float64) (float64, Unit) {
+	if u.System == sys || u.System == AnySystem || sys == AnySystem {
+		return v, u
+	}
+
+	dim := u.Dimension
+	switch {
+	case dim == Temperature:
+		target := Celsius
+		if sys == Imperial {
+			target = Fahrenheit
+		}
+		converted, _ := Convert(v, u, target, 0)
+		return converted, target
+	case density > 0 && dim == Volume && sys == Metric:
+		dim = Mass
+	case density > 0 && dim == Mass && sys == Imperial:
+		dim = Volume
+	}
+
+	base := Gram
+	if dim == Volume {
+		base = Milliliter
+	}
+	amount, err := Convert(v, u, base, density)
+	if err != nil {
+		return v, u
+	}
+	target := fit(amount, dim, sys)
+	return amount / target.Factor, target
+}
+
+// fit returns the unit of the system which fits the amount given in grams
+// or milliliters
+func fit(amount float64, dim Dimension, sys System) Unit {
+	switch {
+	case dim == Mass && sys == Metric:
+		if amount >= Kilogram.Factor {
+			return Kilogram
+		}
+		return Gram
+	case dim == Mass:
+		if amount >= Pound.Factor {
+			return Pound
+		}
+		return Ounce
+	case dim == Volume && sys == Metric:
+		if amount >= Liter.Factor {
+			return Liter
+		}
+		return Milliliter
+	}
+	switch {
+	case amount >= Cup.Factor/4:
+		return Cup
+	case amount >= Tablespoon.Factor:
+		return Tablespoon
+	}
+	return Teaspoon
+}
@@ -0,0 +1,105 @@
+package units_test
+
+import (
+	"github.com/ashkarin/ashkarin-api-test/pkg/units"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Units", func() {
+	It("should look the units up by their names", func() {
+		for name, expected := range map[string]units.Unit{
+			"g":           units.Gram,
+			"Grams":       units.Gram,
+			" tbsp ":      units.Tablespoon,
+			"fluid ounce": units.FluidOunce,
+			"lbs":         units.Pound,
+			"°C":          units.Celsius,
+		} {
+			u, err := units.Lookup(name)
+			Expect(err).NotTo(HaveOccurred(), name)
+			Expect(u).To(Equal(expected), name)
+		}
+
+		for _, name := range []string{"handful", "c", "f"} {
+			_, err := units.Lookup(name)
+			Expect(err).To(MatchError(units.ErrUnknownUnit), name)
+		}
+	})
+
+	It("should convert the units of the same dimension", func() {
+		v, err := units.Convert(1, units.Pound, units.Gram, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(BeNumerically("~", 453.59, 0.01))
+
+		v, _ = units.Convert(2, units.Cup, units.Liter, 0)
+		Expect(v).To(BeNumerically("~", 0.473, 0.001))
+
+		v, _ = units.Convert(100, units.Celsius, units.Fahrenheit, 0)
+		Expect(v).To(BeNumerically("~", 212, 1e-9))
+		v, _ = units.Convert(350, units.Fahrenheit, units.Celsius, 0)
+		Expect(v).To(BeNumerically("~", 176.67, 0.01))
+	})
+
+	It("should convert the mass and the volume by the density", func() {
+		density, ok := units.Density("plain flour")
+		Expect(ok).To(BeTrue())
+		v, err := units.Convert(1, units.Cup, units.Gram, density)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v).To(BeNumerically("~", 125.4, 0.1))
+
+		_, err = units.Convert(1, units.Cup, units.Gram, 0)
+		Expect(err).To(MatchError(units.ErrIncompatible))
+		_, err = units.Convert(1, units.Piece, units.Gram, density)
+		Expect(err).To(MatchError(units.ErrIncompatible))
+	})
+
+	It("should find the density by the longest known name", func() {
+		density, _ := units.Density("Light Brown Sugar")
+		Expect(density).To(Equal(0.93))
+		density, _ = units.Density("sugar")
+		Expect(density).To(Equal(0.85))
+
+		_, ok := units.Density("chicken thighs")
+		Expect(ok).To(BeFalse())
+		_, ok = units.Density("oilseed")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should convert to the units of the system which fit the size", func() {
+		v, u := units.ToSystem(1500, units.Gram, units.Imperial, 0)
+		Expect(u).To(Equal(units.Pound))
+		Expect(v).To(BeNumerically("~", 3.31, 0.01))
+
+		v, u = units.ToSystem(3, units.Ounce, units.Metric, 0)
+		Expect(u).To(Equal(units.Gram))
+		Expect(v).To(BeNumerically("~", 85.05, 0.01))
+
+		v, u = units.ToSystem(2, units.Quart, units.Metric, 0)
+		Expect(u).To(Equal(units.Liter))
+		Expect(v).To(BeNumerically("~", 1.89, 0.01))
+
+		// The metric cooks weigh, the imperial ones measure by volume
+		v, u = units.ToSystem(2, units.Cup, units.Metric, 0.53)
+		Expect(u).To(Equal(units.Gram))
+		Expect(v).To(BeNumerically("~", 250.8, 0.1))
+		v, u = units.ToSystem(250, units.Gram, units.Imperial, 0.53)
+		Expect(u).To(Equal(units.Cup))
+		Expect(v).To(BeNumerically("~", 1.99, 0.01))
+
+		// The units of any system and of the same system are kept
+		v, u = units.ToSystem(1, units.Teaspoon, units.Metric, 1.2)
+		Expect(u).To(Equal(units.Teaspoon))
+		Expect(v).To(Equal(1.0))
+		_, u = units.ToSystem(1, units.Cup, units.Imperial, 0)
+		Expect(u).To(Equal(units.Cup))
+	})
+
+	It("should convert the temperatures in the text", func() {
+		text := "Preheat the oven to 180°C, bake for 20 min at 200 °C"
+		Expect(units.ConvertTemperatures(text, units.Imperial)).
+			To(Equal("Preheat the oven to 356°F, bake for 20 min at 392°F"))
+		Expect(units.ConvertTemperatures(text, units.Metric)).To(Equal(text))
+		Expect(units.ConvertTemperatures("Roast at 350°F", units.Metric)).To(Equal("Roast at 177°C"))
+	})
+})
@@ -6,6 +6,7 @@ if __name__ == "__main__":
     parser = argparse.ArgumentParser(description='Load recipes from JSON files and post them to the server')
     parser.add_argument('--filepath', type=str, help='a path to JSON file')
     parser.add_argument('--address', type=str, default='http://localhost:8080', help='server address')
+    parser.add_argument('--token', type=str, help='import token, the ratings are kept with it')
     args = parser.parse_args()
 
     with open(args.filepath, 'r') as infile:
@@ -17,11 +18,15 @@ if __name__ == "__main__":
                 "name": recipe["name"],
                 "difficulty": recipe["difficulty"],
                 "prepTime": recipe["prepTime"],
-                "vegetarian": False,
+                "ingredients": [{"name": i["name"]} for i in recipe.get("ingredients") or []],
                 "averageRating": averageRating,
                 "ratingsCount": ratingsCount,
             }
             address = "%s/recipes" % (args.address)
-            r = requests.post(address, data=json.dumps(payload))
+            headers = {}
+            if args.token:
+                address = "%s/recipes/import" % (args.address)
+                headers["Authorization"] = "Bearer %s" % (args.token)
+            r = requests.post(address, data=json.dumps(payload), headers=headers)
             print (payload)
             print(r.status_code, r.reason)
\ No newline at end of file